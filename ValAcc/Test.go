@@ -1,16 +1,19 @@
 package main
 
 import (
+	"context"
 	"crypto/sha256"
 	"flag"
 	"fmt"
 	"math/rand"
+	"strings"
 	"time"
 
 	router2 "github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/router"
 
 	"github.com/dustin/go-humanize"
 
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/anchor"
 	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/node"
 	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
 )
@@ -28,11 +31,26 @@ func main() {
 	ChainLimitPtr := flag.Int64("c", 1000, "The number of chains updated while processing this test")
 	TpsLimitPtr := flag.Int64("t", -1, "the tps limit of data generated to run this test. if t < 0, no limit")
 	AccNumberPtr := flag.Int64("a", 1, "the number of accumulator instances used in this test")
+	ShardPtr := flag.String("shard", "", "how chains are sharded over accumulators: modulo or ring.  Defaults to the last run's")
+	VNodesPtr := flag.Int("vnodes", 100, "the number of points on the ring for each accumulator, with -shard ring")
+	AnchorPtr := flag.String("anchor", "", "anchor the global roots to this file (a mock of Factom)")
+	BlockTimePtr := flag.Duration("blocktime", 10*time.Second, "close a block after this long; 0 for no time limit")
+	BlockEntriesPtr := flag.Int64("blockentries", 0, "close a block after this many entries; 0 for no limit")
+	BlockChainsPtr := flag.Int64("blockchains", 0, "close a block after entries to this many chains; 0 for no limit")
+	BlockBytesPtr := flag.Int64("blockbytes", 0, "close a block after this many bytes of entries; 0 for no limit")
+	DataDirPtr := flag.String("datadir", router2.DefaultConfig().DataDir, "the directory holding the databases")
+	BackendPtr := flag.String("backend", "goleveldb", "the database backend: goleveldb, cleveldb, badgerdb, boltdb, rocksdb or memdb")
+	RemotePtr := flag.String("remote", "", "comma separated addresses of accumulators run by cmd/accumulator, replacing -a")
 	flag.Parse()
 	EntryLimit := *EntryLimitPtr
 	ChainLimit := *ChainLimitPtr
 	TpsLimit := *TpsLimitPtr
 	AccNumber := *AccNumberPtr
+	var remotes []string
+	if *RemotePtr != "" {
+		remotes = strings.Split(*RemotePtr, ",")
+		AccNumber = int64(len(remotes))
+	}
 
 	tpsstr := humanize.Comma(TpsLimit)
 	if TpsLimit < 0 {
@@ -44,6 +62,11 @@ func main() {
 	fmt.Println(" -c <number of chains>")
 	fmt.Println(" -t <tps limit ( -1 is none)>")
 	fmt.Println(" -a <number of accumulators>")
+	fmt.Println(" -shard <modulo | ring>")
+	fmt.Println(" -anchor <file to anchor roots to>")
+	fmt.Println(" -remote <address,address,...>")
+	fmt.Println(" -datadir <directory> -backend <goleveldb | memdb | ...>")
+	fmt.Println(" -blocktime <duration> -blockentries <n> -blockchains <n> -blockbytes <n>")
 	fmt.Println("=========================")
 	fmt.Printf(
 		"Entry limit of     %15s\n"+
@@ -58,9 +81,49 @@ func main() {
 	fmt.Println()
 
 	router := new(router2.Router)
+	router.Config = router2.DefaultConfig()
+	router.Config.DataDir = *DataDirPtr
+	router.Config.Backend = *BackendPtr
+	router.Policy = &router2.BlockPolicy{
+		Interval:   *BlockTimePtr,
+		MaxEntries: *BlockEntriesPtr,
+		MaxChains:  *BlockChainsPtr,
+		MaxBytes:   *BlockBytesPtr,
+	}
 	EntryFeed := make(chan node.EntryHash, 10000)
-	router.Init(EntryFeed, int(AccNumber))
-	go router.Run()
+	switch *ShardPtr {
+	case "":
+	case "modulo":
+		router.Strategy = &router2.Modulo{Accumulators: int(AccNumber)}
+	case "ring":
+		router.Strategy = router2.NewRing(int(AccNumber), *VNodesPtr)
+	default:
+		fmt.Printf("unknown shard strategy %s%s\n", *ShardPtr, help)
+		return
+	}
+	if remotes != nil {
+		if err := router.InitRemote(context.Background(), EntryFeed, remotes); err != nil {
+			fmt.Printf("failed to connect to the accumulators: %v\n", err)
+			return
+		}
+	} else if err := router.Init(EntryFeed, int(AccNumber)); err != nil {
+		fmt.Printf("failed to start the accumulators: %v\n", err)
+		return
+	}
+	if *AnchorPtr != "" {
+		anchorer, err := anchor.NewFileAnchorer(*AnchorPtr)
+		if err != nil {
+			fmt.Printf("failed to open the anchor file: %v\n", err)
+			return
+		}
+		router.Anchor = anchor.NewScheduler(router.DB, anchorer, 100, time.Minute)
+	}
+	ctx, shutdown := context.WithCancel(context.Background())
+	routerDone := make(chan struct{})
+	go func() {
+		router.Run(ctx)
+		close(routerDone)
+	}()
 
 	// Validator implementation
 	// Just create a series of hashes to be recorded.
@@ -85,14 +148,14 @@ func main() {
 		total++
 		if i&0xFF == 0 {
 			tps := total / (time.Now().Unix() - types.StartApp.Unix() + 1)
-			for tps > TpsLimit {
+			for TpsLimit >= 0 && tps > TpsLimit {
 				time.Sleep(time.Second)
 				tps = total / (time.Now().Unix() - types.StartApp.Unix() + 1)
 			}
 		}
 	}
 	// Wait for the accumulator to eat up the Entries
-	for len(EntryFeed) == 0 {
+	for len(EntryFeed) > 0 {
 		time.Sleep(100 * time.Millisecond)
 	}
 
@@ -100,7 +163,9 @@ func main() {
 	fmt.Printf("\n====================\nRecorded %s Entries in %s Blocks\n",
 		humanize.Comma(int64(EntryLimit)),
 		humanize.Comma(int64(blockCount)))
-	fmt.Println("Test complete.")
 
-	time.Sleep(1 * time.Second)
+	// Shut down the router, which seals the last block and closes the databases
+	shutdown()
+	<-routerDone
+	fmt.Println("Test complete.")
 }
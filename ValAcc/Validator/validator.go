@@ -0,0 +1,89 @@
+package factoid
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/database"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/node"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
+)
+
+// Validator
+// Accepts full entries (ANodes) from applications, validates them, and stores their content.  Then the
+// hash of each entry is sent on to the router to be accumulated.  The accumulators only ever see the
+// hashes, so the content of an entry can only be found in the Validator's database (see node.GetEntry).
+type Validator struct {
+	DB              *database.DB        // Database holding the content of the entries
+	EntryHashStream chan node.EntryHash // Stream of hashes sent to the router
+}
+
+// NewValidator
+// Create a Validator storing entries in the given database, and sending their hashes to the given stream
+func NewValidator(db *database.DB, entryHashStream chan node.EntryHash) *Validator {
+	v := new(Validator)
+	v.DB = db
+	v.EntryHashStream = entryHashStream
+	return v
+}
+
+// Validate
+// Check that an entry is well formed and can be marshaled without losing any of its data.
+func (v *Validator) Validate(entry *node.ANode) error {
+	if entry.Version != types.Version {
+		return errors.New(fmt.Sprintf("entry version %d is not supported", entry.Version))
+	}
+	if entry.ChainID == (types.Hash{}) {
+		return errors.New("entry has no ChainID")
+	}
+	if len(entry.SubChainIDs) > math.MaxUint16 {
+		return errors.New(fmt.Sprintf("entry has %d SubChainIDs, more than the limit of %d",
+			len(entry.SubChainIDs), math.MaxUint16))
+	}
+	if len(entry.ExtIDs) > math.MaxUint16 {
+		return errors.New(fmt.Sprintf("entry has %d ExtIDs, more than the limit of %d",
+			len(entry.ExtIDs), math.MaxUint16))
+	}
+	for i, extID := range entry.ExtIDs {
+		if len(extID) > math.MaxUint16 {
+			return errors.New(fmt.Sprintf("ExtID %d is %d bytes, more than the limit of %d",
+				i, len(extID), math.MaxUint16))
+		}
+	}
+	if len(entry.Content) > math.MaxUint16 {
+		return errors.New(fmt.Sprintf("content is %d bytes, more than the limit of %d",
+			len(entry.Content), math.MaxUint16))
+	}
+	if entry.Marshal() == nil {
+		return errors.New("entry failed to marshal")
+	}
+	return nil
+}
+
+// Submit
+// Validate the entry, store it in the database keyed by its hash, and send its hash to the router.
+// Returns the hash of the entry.  Returns an error if the entry is invalid, can't be stored, or if the
+// context is done before the hash is accepted by the router.
+func (v *Validator) Submit(ctx context.Context, entry *node.ANode) (*types.Hash, error) {
+	if err := v.Validate(entry); err != nil {
+		return nil, err
+	}
+	data := entry.Marshal()
+	hash := entry.GetHash()
+	if err := v.DB.Put(types.Entry, hash.Bytes(), data); err != nil {
+		return nil, err
+	}
+
+	var eh node.EntryHash
+	eh.SubChains = entry.SubChainIDs
+	eh.ChainID = entry.ChainID
+	eh.EntryHash = *hash
+	select {
+	case v.EntryHashStream <- eh:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return hash, nil
+}
@@ -0,0 +1,68 @@
+package factoid
+
+import (
+	"context"
+	"crypto/sha256"
+	"testing"
+	"time"
+
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/database"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/node"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
+	dbm "github.com/tendermint/tm-db"
+)
+
+func TestSubmit(t *testing.T) {
+	db := new(database.DB)
+	db.InitDB(dbm.NewMemDB())
+	stream := make(chan node.EntryHash, 10)
+	v := NewValidator(db, stream)
+
+	entry := new(node.ANode)
+	entry.Version = types.Version
+	entry.TimeStamp = types.TimeStamp(time.Now().Unix())
+	entry.SubChainIDs = append(entry.SubChainIDs, sha256.Sum256([]byte("sub chain")))
+	entry.ChainID = types.GetChainID(sha256.Sum256([]byte("TestAcc")), entry.SubChainIDs)
+	entry.ExtIDs = append(entry.ExtIDs, types.DataField("an ExtID"))
+	entry.Content = types.DataField("some content for the entry")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	hash, err := v.Submit(ctx, entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *hash != *entry.GetHash() {
+		t.Error("Submit should return the hash of the entry")
+	}
+
+	eh := <-stream
+	if eh.EntryHash != *hash || eh.ChainID != entry.ChainID || len(eh.SubChains) != 1 {
+		t.Error("the EntryHash sent to the router doesn't match the entry")
+	}
+
+	stored, err := node.GetEntry(db, *hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stored.SameAs(*entry) || *stored.GetHash() != *hash {
+		t.Error("the entry read back should be the entry submitted")
+	}
+
+	bad := *entry
+	bad.ChainID = types.Hash{}
+	if _, err := v.Submit(ctx, &bad); err == nil {
+		t.Error("an entry without a ChainID should be rejected")
+	}
+	bad = *entry
+	bad.Content = make(types.DataField, 70000)
+	if _, err := v.Submit(ctx, &bad); err == nil {
+		t.Error("an entry with more content than can be marshaled should be rejected")
+	}
+	if len(stream) != 0 {
+		t.Error("rejected entries should not be sent to the router")
+	}
+	if _, err := node.GetEntry(db, types.Hash{}); err == nil {
+		t.Error("should not find an entry never submitted")
+	}
+}
@@ -2,6 +2,8 @@ package accumulator
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"sort"
 	"time"
@@ -28,18 +30,106 @@ type Accumulator struct {
 	height        types.BlockHeight        // Height of the current block
 	chains        map[types.Hash]*ChainAcc // Chains with new entries in this block
 	entryFeed     chan node.EntryHash      // Stream of entries to be placed into chains
-	control       chan bool                // We are sent a "true" when it is time to end the block
+	control       chan bool                // We are sent a "true" when it is time to end the block (see EndBlock)
 	mdFeed        chan *types.Hash         // Give back the MD Hashes as they are produced
 	previous      *node.Node               // Previous Directory Block
 	EntryCnt      atomic.AtomicInt64       // Count of entries written
 	ChainsInBlock atomic.AtomicInt64       // Count of chains written to
 	ChainCnt      atomic.AtomicInt64       // Count of all chains
+	endBlock      chan chan *BlockResult   // Requests to end the block, answered with the BlockResult
+	done          chan struct{}            // Closed when Run returns
+	WALPath       string                   // Path to the write-ahead log; set before Init.  No log if empty
+	wal           *WAL                     // Write-ahead log of the entries accepted into this block
+	Fanout        int                      // Max entries in the List of a node; zero lists every chain in the directory block
+
+	totalEntries  int64 // We count the entries and chains as we go, but update the atomic counts
+	chainsInBlock int64 //  at the end of each block
+	refused       int64 // Count of entries refused in this block (see acceptEntry)
+}
+
+// BlockResult
+// Describes a block sealed by the Accumulator.  Returned by EndBlock.
+type BlockResult struct {
+	Height         types.BlockHeight // Height of the block sealed
+	DirectoryBlock types.Hash        // Hash of the directory block written for this height
+	MDRoot         types.Hash        // MD Root of the directory block (header + ListMDRoot)
+	EntryCnt       int64             // Count of entries recorded in this block
+	ChainCnt       int64             // Count of chains updated in this block
+	Refused        int64             // Count of entries refused, as they couldn't be logged or checked against the database
+	TotalEntries   int64             // The accumulator's count of entries once the block is sealed, as from Counts
+	TotalChains    int64             // The accumulator's count of chains once the block is sealed, as from Counts
+	SealTime       time.Duration     // Time taken to seal the block
+	Err            error             // Any error writing the block to the database
+}
+
+// Marshal
+// The binary encoding of a BlockResult, so it can be sent back to a router running in another process.
+// Err is sent as its message.
+func (r *BlockResult) Marshal() (data []byte) {
+	data = append(data, r.Height.Bytes()...)
+	data = append(data, r.DirectoryBlock.Bytes()...)
+	data = append(data, r.MDRoot.Bytes()...)
+	data = append(data, types.Uint64Bytes(uint64(r.EntryCnt))...)
+	data = append(data, types.Uint64Bytes(uint64(r.ChainCnt))...)
+	data = append(data, types.Uint64Bytes(uint64(r.Refused))...)
+	data = append(data, types.Uint64Bytes(uint64(r.TotalEntries))...)
+	data = append(data, types.Uint64Bytes(uint64(r.TotalChains))...)
+	data = append(data, types.Uint64Bytes(uint64(r.SealTime))...)
+	var msg string
+	if r.Err != nil {
+		msg = r.Err.Error()
+	}
+	data = append(data, types.Uint32Bytes(uint32(len(msg)))...)
+	data = append(data, msg...)
+	return data
+}
+
+// Unmarshal
+// Extract a BlockResult from its binary encoding.  Returns an error if the unmarshal fails, or the length
+// of the data consumed and a nil.
+func (r *BlockResult) Unmarshal(data []byte) (dataConsumed int, err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = errors.New(fmt.Sprintf("BlockResult Failed to unmarshal %v", rec))
+		}
+	}()
+	d := data
+	data = r.Height.Extract(data)
+	data = r.DirectoryBlock.Extract(data)
+	data = r.MDRoot.Extract(data)
+	var v uint64
+	v, data = types.BytesUint64(data)
+	r.EntryCnt = int64(v)
+	v, data = types.BytesUint64(data)
+	r.ChainCnt = int64(v)
+	v, data = types.BytesUint64(data)
+	r.Refused = int64(v)
+	v, data = types.BytesUint64(data)
+	r.TotalEntries = int64(v)
+	v, data = types.BytesUint64(data)
+	r.TotalChains = int64(v)
+	v, data = types.BytesUint64(data)
+	r.SealTime = time.Duration(v)
+	var msgLen uint32
+	msgLen, data = types.BytesUint32(data)
+	r.Err = nil
+	if msgLen > 0 {
+		r.Err = errors.New(string(data[:msgLen]))
+	}
+	data = data[msgLen:]
+	return len(d) - len(data), nil
 }
 
 // Allocate the HashMap and Channels for this accumulator
 // The ChainID is the Digital Identity of the Accumulator.  We will want to integrate
 // useful digital IDs into the accumulator structure to ensure the integrity of the data
 // collected.
+//
+// If WALPath is set, the entries accepted into the block in flight before a crash are replayed from
+// the write-ahead log.
+//
+// The control and mdFeed channels are kept for compatibility.  New code should call EndBlock,
+// which seals the block and returns a BlockResult directly.
 func (a *Accumulator) Init(db *database.DB, chainID *types.Hash) (
 	EntryFeed chan node.EntryHash, // Return the EntryFeed channel to send ANode Hashes to the accumulator
 	control chan bool, // The control channel signals End of Block to the accumulator
@@ -65,8 +155,26 @@ func (a *Accumulator) Init(db *database.DB, chainID *types.Hash) (
 	a.entryFeed = make(chan node.EntryHash, 10000)
 	a.control = make(chan bool, 1)
 	a.mdFeed = make(chan *types.Hash, 1)
+	a.endBlock = make(chan chan *BlockResult)
+	a.done = make(chan struct{})
 
-	fmt.Sprintf("Starting the Accumulator at height %d\n", a.height)
+	// Rebuild the block in flight from the write-ahead log.  Entries logged for a height already
+	// sealed made it into the database before the log was reset, so they are skipped.
+	if a.WALPath != "" {
+		wal, err := OpenWAL(a.WALPath)
+		if err != nil {
+			panic(fmt.Sprintf("error opening the write-ahead log.\n%v", err))
+		}
+		_, err = wal.Replay(func(height types.BlockHeight, entry node.EntryHash) {
+			if height == a.height {
+				a.addEntry(entry) // An entry refused is counted in the BlockResult of the block
+			}
+		})
+		if err != nil {
+			panic(fmt.Sprintf("error replaying the write-ahead log.\n%v", err))
+		}
+		a.wal = wal
+	}
 
 	return a.entryFeed, a.control, a.mdFeed
 }
@@ -75,124 +183,344 @@ func (a *Accumulator) GetEntryFeed() chan node.EntryHash {
 	return a.entryFeed
 }
 
-func (a *Accumulator) Run() {
-	var totalEntries int64  // We count the entries and chains as we go, but update the atomic counts
-	var ChainsInBlock int64 //  at the end of each block
+// Counts
+// Returns the count of entries written and the count of chains written to, as of the last block sealed
+func (a *Accumulator) Counts() (entries, chains int64) {
+	return a.EntryCnt.Load(), a.ChainCnt.Load()
+}
 
-	var goWrites atomic.AtomicInt
+// Height
+// Returns the height of the block being accumulated.  Only safe to call when Run is not running.
+func (a *Accumulator) Height() types.BlockHeight {
+	return a.height
+}
 
-	for {
-		// While we are processing a block
-	block:
-		for {
-
-			// Block processing involves pulling Entries out of the entryFeed and adding
-			// it to the Merkle DAG (MD)
-			select {
-			case ctl := <-a.control: // Have we been asked to end the block?
-				if ctl {
-					println("Processing EOB ", a.height)
-					a.height++
-					break block // Break block processing
-				}
-			default:
-				select {
-				case entry := <-a.entryFeed: // Get the next ANode
-					chain := a.chains[entry.ChainID] // See if we have a chain for it
-					totalEntries++
-					if chain == nil { // If we don't have a chain for it, then we add one to our tmp state
-						ChainsInBlock++
-						chain = NewChainAcc(*a.DB, entry, a.height) // Create our collector for this chain
-						a.chains[entry.ChainID] = chain             // Add it to our tmp state
-						chain.MD.AddToChain(entry.EntryHash)        // Add this entry to our chain state
-					} else {
-						// This is where we make sure every Entry added to a chain is a non-duplicate to all
-						// entries.  This assumes that the chains for an accumulator are unique to that accumulator,
-						// which is true by design.  So if the entry isn't in the chain right now, and not in the db,
-						// then it is unique.
-						if chain.entries[entry.EntryHash] == 0 { // Added this entry to this chain already?
-							if a.DB.Get(types.EntryNode, entry.EntryHash.Bytes()) == nil { // Have the entry in the DB already?
-								chain.entries[entry.EntryHash] = 1   // No? Then mark it in the chain
-								chain.MD.AddToChain(entry.EntryHash) // Add it to the chain
-							}
-						}
-					}
-				default:
-					time.Sleep(100 * time.Millisecond) // If there is nothing to do, pause a bit
-				}
-			}
+// AlignHeight
+// Skip ahead to start accumulating the given height, so the accumulator seals the same heights as the
+// others under the router.  Used when accumulators are added, or are behind after chains were moved.  A block
+// in flight can't be moved to another height, and Run must not be running.
+func (a *Accumulator) AlignHeight(height types.BlockHeight) error {
+	if len(a.chains) > 0 && height != a.height {
+		return errors.New(fmt.Sprintf("can't move to height %d with a block in flight at height %d", height, a.height))
+	}
+	if height < a.height {
+		return errors.New(fmt.Sprintf("can't move back to height %d from height %d", height, a.height))
+	}
+	a.height = height
+	return nil
+}
+
+// Chains
+// Returns the ChainIDs of all the chains held by the accumulator, not counting the chain of directory blocks.
+func (a *Accumulator) Chains() (chains []types.Hash, err error) {
+	err = a.DB.ForEach(types.NodeHead, func(key, value []byte) bool {
+		var chainID types.Hash
+		chainID.Extract(key)
+		if chainID != *a.chainID {
+			chains = append(chains, chainID)
 		}
+		return true
+	})
+	return chains, err
+}
+
+// Pending
+// Returns true if there is a block in flight, such as one replayed from the write-ahead log.  Only safe to
+// call when Run is not running.
+func (a *Accumulator) Pending() bool {
+	return len(a.chains) > 0
+}
+
+// SealPending
+// Seal the block in flight, if there is one, before Run is started.  Used to reach a block boundary (after
+// the write-ahead log was replayed) before moving chains.
+func (a *Accumulator) SealPending() *BlockResult {
+	if len(a.chains) == 0 {
+		return nil
+	}
+	return a.sealBlock()
+}
+
+// Seal
+// Seal the block in flight, even an empty one, before Run is started.  Used by the router to seal the same
+// height on every accumulator, so the height has a global root.
+func (a *Accumulator) Seal() *BlockResult {
+	return a.sealBlock()
+}
+
+// Close
+// Close the write-ahead log and the database of an accumulator that is not running.  Run does this itself
+// when it stops.
+func (a *Accumulator) Close() error {
+	var err error
+	if a.wal != nil {
+		err = a.wal.Close()
+	}
+	if dbErr := a.DB.Close(); dbErr != nil && err == nil {
+		err = dbErr
+	}
+	return err
+}
 
-		if goWrites.Load() > 0 {
-			fmt.Println("Waiting on", goWrites.Load(), "database updates.")
-			for goWrites.Load() > 0 {
-				time.Sleep(1 * time.Second)
+// EndBlock
+// Seal the block currently being accumulated and return the BlockResult describing it.  Any entries
+// already sitting in the entryFeed when EndBlock is called are included in the block.  EndBlock
+// returns an error if the context is done before the block is sealed, if the Accumulator has stopped,
+// or if writing the block failed.
+func (a *Accumulator) EndBlock(ctx context.Context) (*BlockResult, error) {
+	request := make(chan *BlockResult, 1) // Buffered, so Run never stalls if we walk away
+	select {
+	case a.endBlock <- request:
+	case <-a.done:
+		return nil, errors.New("the accumulator has stopped")
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	select {
+	case result := <-request:
+		return result, result.Err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Run
+// Pull entries off the entryFeed and add them to their chains until asked to end the block, either
+// by a call to EndBlock or by a true on the control channel.
+//
+// When the context is done, Run seals the block in flight if there is one (including anything still in the
+// entryFeed), closes the database, and returns any error encountered doing so.  A router closes the last
+// block itself before stopping its accumulators, so it gets a global root, and nothing is left to seal.
+func (a *Accumulator) Run(ctx context.Context) error {
+	defer close(a.done)
+	for {
+		select {
+		case <-ctx.Done(): // Have we been asked to shut down?
+			for len(a.entryFeed) > 0 {
+				a.takeEntry(<-a.entryFeed)
+			}
+			var err error
+			if result := a.SealPending(); result != nil {
+				err = result.Err
+			}
+			if cErr := a.Close(); cErr != nil && err == nil {
+				err = cErr
+			}
+			return err
+		case request := <-a.endBlock: // Have we been asked to end the block?
+			request <- a.sealBlock()
+		case ctl := <-a.control: // Compatibility with the control channel returned by Init
+			if ctl {
+				result := a.sealBlock()
+				a.mdFeed <- result.MDRoot.Copy()
 			}
+		case entry := <-a.entryFeed: // Get the next EntryHash
+			a.takeEntry(entry)
 		}
+	}
+}
 
-		var chainEntries []node.NEList
-		for _, v := range a.chains {
-			v.Node.ListMDRoot = *v.MD.GetMDRoot()
-			v.Node.EntryList = v.MD.HashList
-			v.Node.IsNode = false
+// isDuplicate
+// Returns true if the entry was added to its chain already, in this block or a block sealed before.  Returns
+// an error if the database can't be searched for the entry.
+func (a *Accumulator) isDuplicate(entry node.EntryHash) (bool, error) {
+	// This is where we make sure every Entry added to a chain is a non-duplicate to all
+	// entries.  This assumes that the chains for an accumulator are unique to that accumulator,
+	// which is true by design.  So if the entry isn't in the chain right now, and not in the db,
+	// then it is unique.
+	if chain := a.chains[entry.ChainID]; chain != nil && chain.entries[entry.EntryHash] != 0 { // Added this entry to this chain already?
+		return true, nil
+	}
+	return a.DB.Has(types.EntryNode, entry.EntryHash.Bytes()) // Have the entry in the DB already?
+}
 
-			tNode := v.Node
-			go func() {
-				goWrites.Add(1)
-				tNode.Put(a.DB)
-				goWrites.Add(-1)
-			}()
+// addEntry
+// Add an entry to the chain it belongs to in this block.  Duplicates are dropped.  Returns an error, and
+// counts the entry refused, if it can't be checked against the database or its chain can't be picked up.
+func (a *Accumulator) addEntry(entry node.EntryHash) error {
+	a.totalEntries++
+	duplicate, err := a.isDuplicate(entry)
+	if err != nil {
+		return a.refuse(entry, err)
+	}
+	if duplicate {
+		return nil
+	}
+	chain, err := a.getChain(entry)
+	if err != nil {
+		return a.refuse(entry, err)
+	}
+	a.add(chain, entry)
+	return nil
+}
 
-			ne := new(node.NEList)
-			ne.ChainID = v.Node.ChainID
-			ne.MDRoot = v.Node.ListMDRoot
-			chainEntries = append(chainEntries, *ne)
+// getChain
+// Returns the collector for the entry's chain in this block, or a new one that picks up where the chain's
+// last node left off.  A new collector is only added to the block with its first entry, by add.
+func (a *Accumulator) getChain(entry node.EntryHash) (*ChainAcc, error) {
+	if chain := a.chains[entry.ChainID]; chain != nil {
+		return chain, nil
+	}
+	return NewChainAcc(*a.DB, entry, a.height)
+}
 
-		}
+// add
+// Add an entry known not to be a duplicate to its chain's collector, adding the collector to this block
+// if it is new
+func (a *Accumulator) add(chain *ChainAcc, entry node.EntryHash) {
+	if a.chains[entry.ChainID] == nil { // If we don't have the chain yet, then we add it to our tmp state
+		a.chainsInBlock++
+		a.chains[entry.ChainID] = chain
+	}
+	chain.entries[entry.EntryHash] = 1   // Mark it in the chain
+	chain.MD.AddToChain(entry.EntryHash) // Add it to the chain
+}
 
-		sort.Slice(chainEntries, func(i, j int) bool {
-			return bytes.Compare(chainEntries[i].ChainID[:], chainEntries[j].ChainID[:]) < 0
-		})
+// refuse
+// Count an entry that can't be added to the block as refused rather than entered, and return why
+func (a *Accumulator) refuse(entry node.EntryHash, err error) error {
+	a.totalEntries--
+	a.refused++
+	return errors.New(fmt.Sprintf("entry %x refused: %v", entry.EntryHash, err))
+}
 
-		// Print some statistics
-		var sum int
-		for _, v := range a.chains {
-			sum += len(v.MD.HashList)
+// acceptEntry
+// Add an entry to its chain, recording it in the write-ahead log first.  An entry that can't be written to
+// the log is refused, as it would be lost in a crash before the block is sealed, and so is an entry that
+// can't be checked against the database or whose chain can't be picked up from it.  A refused entry is not added to the block, and the error is
+// returned.  Refused entries are counted in the BlockResult of the block.
+func (a *Accumulator) acceptEntry(entry node.EntryHash) error {
+	a.totalEntries++
+	duplicate, err := a.isDuplicate(entry)
+	if err != nil {
+		return a.refuse(entry, err)
+	}
+	if duplicate {
+		return nil
+	}
+	chain, err := a.getChain(entry)
+	if err != nil {
+		return a.refuse(entry, err)
+	}
+	if a.wal != nil {
+		if err := a.wal.Append(a.height, entry); err != nil {
+			return a.refuse(entry, errors.New(fmt.Sprintf("it could not be written to the write-ahead log: %v", err)))
 		}
+	}
+	a.add(chain, entry)
+	return nil
+}
 
-		a.EntryCnt.Store(totalEntries)
-		a.ChainsInBlock.Store(ChainsInBlock)
-		a.ChainCnt.Add(ChainsInBlock)
-		ChainsInBlock = 0
+// takeEntry
+// Accept an entry from the entryFeed, where there is no one to return an error to.  A refusal is counted,
+// and reported in the BlockResult of the block.
+func (a *Accumulator) takeEntry(entry node.EntryHash) {
+	a.acceptEntry(entry)
+}
 
-		// Calculate the ListMDRoot for all the accumulated MDRoots for all the chains
-		MDAcc := new(merkleDag.MD)
-		for _, v := range chainEntries {
-			MDAcc.AddToChain(v.MDRoot)
-		}
+// sealBlock
+// Write all the chains updated in this block, build the directory block over them, and start the
+// next block.
+func (a *Accumulator) sealBlock() (result *BlockResult) {
+	start := time.Now()
+	result = new(BlockResult)
+	result.Height = a.height
 
-		// Populate the directory block with the data collected over the last block period.
-		directoryBlock := new(node.Node)
-		directoryBlock.Version = types.Version
-		directoryBlock.ChainID = *a.chainID
-		directoryBlock.BHeight = a.height
-		if directoryBlock.SequenceNum > 0 {
-			directoryBlock.Previous = *a.previous.GetHash()
-		}
-		directoryBlock.SequenceNum = types.Sequence(a.height)
-		directoryBlock.TimeStamp = types.TimeStamp(time.Now().UnixNano())
-		directoryBlock.IsNode = true
-		lMDR := MDAcc.GetMDRoot()
-		if lMDR != nil {
-			directoryBlock.ListMDRoot = *lMDR
+	// Everything already submitted before the end of block goes into this block
+	for len(a.entryFeed) > 0 {
+		a.takeEntry(<-a.entryFeed)
+	}
+
+	// Every chain node, index update and the directory block for this height go into one batch, so the
+	// database holds either the whole block or none of it.
+	batch := a.DB.NewBatch()
+	defer batch.Close()
+
+	var chainEntries []node.NEList
+	for _, v := range a.chains {
+		v.Node.ListMDRoot = *v.MD.GetMDRoot()
+		v.Node.EntryList = v.MD.HashList
+		v.Node.IsNode = false
+
+		if err := v.Put(batch); err != nil && result.Err == nil {
+			result.Err = err
 		}
 
-		// Write the directory
-		directoryBlock.Put(a.DB)
+		ne := new(node.NEList)
+		ne.ChainID = v.Node.ChainID
+		ne.MDRoot = *v.Node.GetMDRoot() // The chain node's header and ListMDRoot
+		chainEntries = append(chainEntries, *ne)
+
+		result.EntryCnt += int64(len(v.MD.HashList))
+	}
+	result.ChainCnt = int64(len(a.chains))
+	result.Refused = a.refused
+
+	sort.Slice(chainEntries, func(i, j int) bool {
+		return bytes.Compare(chainEntries[i].ChainID[:], chainEntries[j].ChainID[:]) < 0
+	})
+
+	// Build the intermediate nodes over ranges of chains, leaving the top level for the directory block
+	chainEntries, err := BuildNodeBlocks(batch, a.height, chainEntries, a.Fanout)
+	if err != nil && result.Err == nil {
+		result.Err = err
+	}
+
+	// Calculate the ListMDRoot for all the accumulated MDRoots in the top level
+	MDAcc := new(merkleDag.MD)
+	for _, v := range chainEntries {
+		MDAcc.AddToChain(v.MDRoot)
+	}
 
-		a.mdFeed <- directoryBlock.GetMDRoot()
+	// Populate the directory block with the data collected over the last block period.
+	directoryBlock := new(node.Node)
+	directoryBlock.Version = types.Version
+	directoryBlock.ChainID = *a.chainID
+	directoryBlock.BHeight = a.height
+	if a.previous != nil { // Directory blocks are numbered in sequence, even if heights were skipped (see AlignHeight)
+		directoryBlock.Previous = *a.previous.GetHash()
+		directoryBlock.SequenceNum = a.previous.SequenceNum + 1
+	}
+	directoryBlock.TimeStamp = types.TimeStamp(time.Now().UnixNano())
+	directoryBlock.IsNode = true
+	directoryBlock.List = chainEntries
+	lMDR := MDAcc.GetMDRoot()
+	if lMDR != nil {
+		directoryBlock.ListMDRoot = *lMDR
+	}
 
-		// Clear out all the chain heads, to start another round of accumulation in the next block
-		a.chains = make(map[types.Hash]*ChainAcc, 1000)
+	if err := directoryBlock.Put(batch); err != nil && result.Err == nil {
+		result.Err = err
 	}
+	if result.Err == nil {
+		result.Err = batch.Write()
+	}
+
+	result.DirectoryBlock = *directoryBlock.GetHash()
+	result.MDRoot = *directoryBlock.GetMDRoot()
+	result.SealTime = time.Since(start)
+
+	// If the block could not be written, nothing was committed.  Leave the block open, so it is
+	// written by the next EndBlock.  The router calls EndBlock again for this height before any other
+	// accumulator seals the next one (see Router.closeBlock).
+	if result.Err != nil {
+		return result
+	}
+
+	// Only a block written counts, so a block retried after a failed write is counted once
+	a.EntryCnt.Store(a.totalEntries)
+	a.ChainsInBlock.Store(a.chainsInBlock)
+	a.ChainCnt.Add(a.chainsInBlock)
+	a.chainsInBlock = 0
+	result.TotalEntries, result.TotalChains = a.Counts()
+
+	// Clear out all the chain heads, to start another round of accumulation in the next block
+	if a.wal != nil {
+		a.wal.Reset() // If this fails, the entries of the sealed block are skipped on replay anyway
+	}
+	a.previous = directoryBlock
+	a.refused = 0
+	a.height++
+	a.chains = make(map[types.Hash]*ChainAcc, 1000)
+	return result
 }
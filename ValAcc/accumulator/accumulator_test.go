@@ -0,0 +1,262 @@
+package accumulator
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/database"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/merkleDag"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/node"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
+	dbm "github.com/tendermint/tm-db"
+)
+
+// GetTestAccumulator
+// Build an Accumulator over an in memory database for use in tests
+func GetTestAccumulator(t *testing.T) *Accumulator {
+	db := new(database.DB)
+	db.InitDB(dbm.NewMemDB())
+	chainID := types.Hash(sha256.Sum256([]byte("TestAcc DID")))
+	acc := new(Accumulator)
+	acc.Init(db, &chainID)
+	return acc
+}
+
+// GetTestEntry
+// Build an EntryHash for the given chain and entry numbers
+func GetTestEntry(chain, entry int) (eh node.EntryHash) {
+	eh.ChainID = sha256.Sum256([]byte(fmt.Sprint("chain ", chain)))
+	eh.EntryHash = sha256.Sum256([]byte(fmt.Sprint("chain ", chain, " entry ", entry)))
+	return eh
+}
+
+func TestEndBlock(t *testing.T) {
+	acc := GetTestAccumulator(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	go acc.Run(ctx)
+
+	for c := 0; c < 3; c++ {
+		for e := 0; e < 5; e++ {
+			acc.GetEntryFeed() <- GetTestEntry(c, e)
+		}
+	}
+	acc.GetEntryFeed() <- GetTestEntry(0, 0) // A duplicate is not recorded twice
+
+	result, err := acc.EndBlock(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Height != 0 || result.EntryCnt != 15 || result.ChainCnt != 3 {
+		t.Errorf("expected height 0 with 15 entries over 3 chains, got height %d with %d entries over %d chains",
+			result.Height, result.EntryCnt, result.ChainCnt)
+	}
+	dbHash := acc.DB.GetInt32(types.DirectoryBlockHeight, 0)
+	if !bytes.Equal(dbHash, result.DirectoryBlock[:]) {
+		t.Error("the directory block for height 0 should be the one returned in the BlockResult")
+	}
+
+	var directoryBlock node.Node
+	if _, err := directoryBlock.Unmarshal(acc.DB.Get(types.Node, dbHash)); err != nil {
+		t.Fatal(err)
+	}
+	if *directoryBlock.GetMDRoot() != result.MDRoot {
+		t.Error("the MDRoot in the BlockResult should be the MDRoot of the directory block")
+	}
+
+	acc.GetEntryFeed() <- GetTestEntry(3, 0)
+	result2, err := acc.EndBlock(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result2.Height != 1 || result2.EntryCnt != 1 || result2.ChainCnt != 1 {
+		t.Errorf("expected height 1 with 1 entry over 1 chain, got height %d with %d entries over %d chains",
+			result2.Height, result2.EntryCnt, result2.ChainCnt)
+	}
+	var directoryBlock2 node.Node
+	if _, err := directoryBlock2.Unmarshal(acc.DB.Get(types.Node, result2.DirectoryBlock[:])); err != nil {
+		t.Fatal(err)
+	}
+	if directoryBlock2.Previous != result.DirectoryBlock {
+		t.Error("the second directory block should point back to the first")
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	memDB := dbm.NewMemDB()
+	db := new(database.DB)
+	db.InitDB(memDB)
+	chainID := types.Hash(sha256.Sum256([]byte("TestAcc DID")))
+	acc := new(Accumulator)
+	acc.Init(db, &chainID)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- acc.Run(ctx) }()
+
+	for e := 0; e < 10; e++ {
+		acc.GetEntryFeed() <- GetTestEntry(0, e)
+	}
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("the accumulator did not shut down")
+	}
+
+	if _, err := acc.EndBlock(context.Background()); err == nil {
+		t.Error("EndBlock should fail once the accumulator has stopped")
+	}
+
+	// The block in flight should have been sealed before the database was closed
+	db2 := new(database.DB)
+	db2.InitDB(memDB)
+	dbHash := db2.GetInt32(types.DirectoryBlockHeight, 0)
+	if dbHash == nil {
+		t.Fatal("the block in flight was not sealed on shutdown")
+	}
+	chain := GetTestEntry(0, 0).ChainID
+	headHash := db2.Get(types.NodeHead, chain[:])
+	var head node.Node
+	if _, err := head.Unmarshal(db2.Get(types.Node, headHash)); err != nil {
+		t.Fatal(err)
+	}
+	if head.ListMDRoot == (types.Hash{}) {
+		t.Error("the chain written on shutdown should have a ListMDRoot")
+	}
+}
+
+func TestDuplicateAcrossBlocks(t *testing.T) {
+	acc := GetTestAccumulator(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	go acc.Run(ctx)
+
+	for e := 0; e < 5; e++ {
+		acc.GetEntryFeed() <- GetTestEntry(0, e)
+	}
+	if _, err := acc.EndBlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	chainID := GetTestEntry(0, 0).ChainID
+	firstHash := acc.DB.Get(types.NodeHead, chainID[:])
+
+	acc.GetEntryFeed() <- GetTestEntry(0, 0) // Recorded in the last block
+	acc.GetEntryFeed() <- GetTestEntry(0, 5)
+	result, err := acc.EndBlock(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.EntryCnt != 1 {
+		t.Errorf("an entry recorded in a previous block should be rejected.  Expected 1 entry, got %d", result.EntryCnt)
+	}
+
+	loc, err := node.GetEntryLocation(acc.DB, GetTestEntry(0, 5).EntryHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loc.BHeight != 1 || loc.Position != 0 {
+		t.Errorf("expected the entry at height 1 position 0, got height %d position %d", loc.BHeight, loc.Position)
+	}
+	if loc.Node.SequenceNum != 1 || !bytes.Equal(loc.Node.Previous[:], firstHash) {
+		t.Error("the second node in the chain should follow the first")
+	}
+}
+
+func TestChainsAtHeight(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	for c := 0; c < 5; c++ {
+		acc.acceptEntry(GetTestEntry(c, 0))
+	}
+	if result := acc.sealBlock(); result.Err != nil {
+		t.Fatal(result.Err)
+	}
+	acc.acceptEntry(GetTestEntry(2, 1))
+	if result := acc.sealBlock(); result.Err != nil {
+		t.Fatal(result.Err)
+	}
+
+	for height, expected := range []int{5, 1} {
+		list, err := node.GetChainsAtHeight(acc.DB, types.BlockHeight(height))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) != expected {
+			t.Fatalf("expected %d chains at height %d, got %d", expected, height, len(list))
+		}
+		directoryBlock, _ := node.GetDirectoryBlock(acc.DB, types.BlockHeight(height))
+		md := new(merkleDag.MD)
+		for i, ne := range list {
+			if i > 0 && bytes.Compare(list[i-1].ChainID[:], ne.ChainID[:]) >= 0 {
+				t.Error("the chains should be in ChainID order")
+			}
+			md.AddToChain(ne.MDRoot)
+		}
+		if *md.GetMDRoot() != directoryBlock.ListMDRoot {
+			t.Errorf("the directory block's ListMDRoot at height %d should be the MD of its List", height)
+		}
+	}
+
+	// The MDRoot listed for the chain at height 1 is the MDRoot of the chain's head node
+	list, _ := node.GetChainsAtHeight(acc.DB, 1)
+	chainID := GetTestEntry(2, 0).ChainID
+	head, err := node.GetNode(acc.DB, acc.DB.Get(types.NodeHead, chainID[:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list[0].ChainID != chainID || list[0].MDRoot != *head.GetMDRoot() {
+		t.Error("the directory block should list the MDRoot of the chain node")
+	}
+
+	if _, err := node.GetChainsAtHeight(acc.DB, 2); err == nil {
+		t.Error("there is no directory block at height 2 yet")
+	}
+}
+
+func TestBlockResultMarshal(t *testing.T) {
+	for _, err := range []error{nil, errors.New("disk full")} {
+		result := new(BlockResult)
+		result.Height = 12
+		result.DirectoryBlock = sha256.Sum256([]byte("directory block"))
+		result.MDRoot = sha256.Sum256([]byte("md root"))
+		result.EntryCnt = 1000
+		result.ChainCnt = 10
+		result.Refused = 2
+		result.TotalEntries = 5000
+		result.TotalChains = 40
+		result.SealTime = 3 * time.Millisecond
+		result.Err = err
+
+		data := result.Marshal()
+		got := new(BlockResult)
+		n, uErr := got.Unmarshal(append(data, 7)) // Trailing data is left alone
+		if uErr != nil {
+			t.Fatal(uErr)
+		}
+		if n != len(data) {
+			t.Errorf("expected %d bytes consumed, got %d", len(data), n)
+		}
+		if got.Height != result.Height || got.DirectoryBlock != result.DirectoryBlock || got.MDRoot != result.MDRoot ||
+			got.EntryCnt != result.EntryCnt || got.ChainCnt != result.ChainCnt || got.Refused != result.Refused ||
+			got.TotalEntries != result.TotalEntries || got.TotalChains != result.TotalChains || got.SealTime != result.SealTime {
+			t.Errorf("expected %+v, got %+v", result, got)
+		}
+		if fmt.Sprint(got.Err) != fmt.Sprint(result.Err) {
+			t.Errorf("expected error %v, got %v", result.Err, got.Err)
+		}
+		if _, uErr := got.Unmarshal(data[:len(data)-1]); uErr == nil {
+			t.Error("a truncated BlockResult should fail to unmarshal")
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package accumulator
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/database"
@@ -10,24 +12,47 @@ import (
 )
 
 // ChainAcc
-// Tracks the construction of the Merkle DAG and collects the Hash sequence to build the MD
+// Tracks the construction of the Merkle DAG and collects the Hash sequence to build the MD.  The MD runs
+// across all the blocks of the chain, so the node's ListMDRoot covers every entry in the chain.
 type ChainAcc struct {
-	entries map[types.Hash]int // list of entry hashes we are collecting
-	Node    node.Node          // The node we are building
-	MD      *merkleDag.MD      // The class for creating the MD and MD Roots
+	entries    map[types.Hash]int // list of entry hashes we are collecting
+	Node       node.Node          // The node we are building
+	MD         *merkleDag.MD      // The class for creating the MD and MD Roots
+	mdPrevious types.Hash         // Hash of the MDNode for the previous node in this chain
 }
 
-func NewChainAcc(DB database.DB, eHash node.EntryHash, bHeight types.BlockHeight) *ChainAcc {
+// NewChainAcc
+// Start collecting the entries of a chain for the block at the given height, picking up the chain's MD where
+// its last node left off.  Returns an error if the last node of the chain or its MD state can't be read.
+func NewChainAcc(DB database.DB, eHash node.EntryHash, bHeight types.BlockHeight) (*ChainAcc, error) {
 	chainAcc := new(ChainAcc)
 	chainAcc.entries = make(map[types.Hash]int)
 	previousHash := DB.Get(types.NodeHead, eHash.ChainID[:])
 	if previousHash != nil {
 		previousBytes := DB.Get(types.Node, previousHash[:])
 		var previous node.Node
-		previous.Unmarshal(previousBytes)
-		chainAcc.Node.SequenceNum = previous.SequenceNum
-		chainAcc.Node.MarshalCache = previousBytes
-		chainAcc.Node.Previous = *previous.GetHash()
+		if _, err := previous.Unmarshal(previousBytes); err != nil {
+			return nil, errors.New(fmt.Sprintf("error unmarshaling the head of chain %x.\n%v", eHash.ChainID, err))
+		}
+		chainAcc.Node.SequenceNum = previous.SequenceNum + 1
+		chainAcc.Node.Previous.Extract(previousHash) // The key of the head is the hash of the previous node
+
+		// Pick up the MD of the chain where the previous node left off
+		if mdBytes := DB.Get(types.MDState, previousHash); mdBytes != nil {
+			var mdNode merkleDag.MDNode
+			if _, err := mdNode.Unmarshal(mdBytes); err != nil {
+				return nil, errors.New(fmt.Sprintf("error unmarshaling the MD state of chain %x.\n%v", eHash.ChainID, err))
+			}
+			md, err := mdNode.GetMD()
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("error restoring the MD state of chain %x.\n%v", eHash.ChainID, err))
+			}
+			chainAcc.MD, err = merkleDag.NewMD(md.Count(), md.CompressState()) // Start a new block of the MD
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("error continuing the MD of chain %x.\n%v", eHash.ChainID, err))
+			}
+			chainAcc.mdPrevious = *mdNode.GetHash()
+		}
 	}
 	chainAcc.Node.Version = types.Version
 	chainAcc.Node.SubChainIDs = eHash.SubChains
@@ -35,6 +60,21 @@ func NewChainAcc(DB database.DB, eHash node.EntryHash, bHeight types.BlockHeight
 	chainAcc.Node.TimeStamp = types.TimeStamp(time.Now().UnixNano())
 	chainAcc.Node.BHeight = bHeight
 	chainAcc.Node.IsNode = false
-	chainAcc.MD = new(merkleDag.MD)
-	return chainAcc
+	if chainAcc.MD == nil {
+		chainAcc.MD = new(merkleDag.MD)
+	}
+	return chainAcc, nil
+}
+
+// Put
+// Write the node for this chain, and the state of the chain's MD at this node, to the database or a batch.
+// The node must be complete.
+func (c *ChainAcc) Put(db database.Store) error {
+	if err := c.Node.Put(db); err != nil {
+		return err
+	}
+	mdNode := merkleDag.NewMDNode(c.MD)
+	mdNode.SequenceNumber = uint32(c.Node.SequenceNum)
+	mdNode.Previous = c.mdPrevious
+	return db.Put(types.MDState, c.Node.GetHash().Bytes(), mdNode.Bytes())
 }
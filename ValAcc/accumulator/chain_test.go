@@ -3,26 +3,38 @@ package accumulator
 import (
 	"crypto/sha256"
 	"testing"
+
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/merkleDag"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/node"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
 )
 
+// newTestChainAcc
+// Get a ChainAcc with an empty MD, without going through a database
+func newTestChainAcc() *ChainAcc {
+	chain := new(ChainAcc)
+	chain.MD = new(merkleDag.MD)
+	return chain
+}
+
 func TestMerkleBuilding(t *testing.T) {
 	hash := sha256.Sum256([]byte("testdata"))
-	chain := new(ChainAcc)
+	chain := newTestChainAcc()
 
 	// This test depends on the observation that the non blank entries in c.MD must be non-zero
 	// for every set bit in the count of the entries added to c.MD.  So all we have to do to check the algorithm
 	// here is to add entries, then check that the count of entries added predicts the slots in c.MD that are !nil
 	for eCnt := 1; eCnt < 65000; eCnt++ {
 		hash := sha256.Sum256(hash[:]) // Get a new hash
-		chain.AddToChain(hash)         // Add the new hash to the chain
+		chain.MD.AddToChain(hash)      // Add the new hash to the chain
 
 		cnt := eCnt // Get a count we can shift to compare the current count with c.MD
-		for i, v := range chain.MD {
+		for i, v := range chain.MD.MD {
 			if cnt&1 == 1 && v == nil { // If I have a bit set, v can't be nil
-				t.Errorf("Expected the MD entry %d to be !nil. MD %s and entries %x ", i, chain.PrintMR(), eCnt)
+				t.Errorf("Expected the MD entry %d to be !nil. MD %s and entries %x ", i, chain.MD.PrintMR(), eCnt)
 				return
 			} else if cnt&1 == 0 && v != nil { // If I have a bit clear, v can't be set
-				t.Errorf("Expected the MD entry %d to be nil.  MD %s and entries %x ", i, chain.PrintMR(), eCnt)
+				t.Errorf("Expected the MD entry %d to be nil.  MD %s and entries %x ", i, chain.MD.PrintMR(), eCnt)
 				return
 			}
 			cnt = cnt >> 1
@@ -33,7 +45,7 @@ func TestMerkleBuilding(t *testing.T) {
 
 func TestMerkleInclusion(t *testing.T) {
 	hash := sha256.Sum256([]byte("testdata"))
-	chain := new(ChainAcc)
+	chain := newTestChainAcc()
 
 	// This test leverages the fact that GetMDRoot() is non-destructive.  So we build up a
 	// a MDRoot up to our limit, but after each additional entry, we redo the process with the entries
@@ -43,15 +55,15 @@ func TestMerkleInclusion(t *testing.T) {
 	// given the same entries
 	for eCnt := 1; eCnt < 2100; eCnt++ {
 		hash := sha256.Sum256(hash[:]) // Get a new hash
-		chain.AddToChain(hash)         // Add the new hash to the chain
+		chain.MD.AddToChain(hash)      // Add the new hash to the chain
 
-		MDRoot := chain.GetMDRoot()
+		MDRoot := chain.MD.GetMDRoot()
 
-		copyChain := new(ChainAcc)
-		for _, v := range chain.HashList {
-			copyChain.AddToChain(v)
+		copyChain := newTestChainAcc()
+		for _, v := range chain.MD.HashList {
+			copyChain.MD.AddToChain(v)
 		}
-		copyMDRoot := chain.GetMDRoot()
+		copyMDRoot := copyChain.MD.GetMDRoot()
 
 		if *MDRoot != *copyMDRoot {
 			t.Error("The same entries in the same order should have the same MDRoot")
@@ -64,20 +76,20 @@ func TestMerkleInclusion(t *testing.T) {
 	// to build a MDRoot
 	for eCnt := 64; eCnt < 65; eCnt++ {
 		hash := sha256.Sum256(hash[:]) // Get a new hash
-		chain.AddToChain(hash)         // Add the new hash to the chain
+		chain.MD.AddToChain(hash)      // Add the new hash to the chain
 
-		MDRoot := chain.GetMDRoot() // This is the MDRoot of the unmodified data
+		MDRoot := chain.MD.GetMDRoot() // This is the MDRoot of the unmodified data
 
 		for i := 0; i < eCnt; i++ { // Run eCnt tests (one for every entry in chain
 			for j := 0; j < eCnt; j++ { // Modify each of the entries in chain and compute a MDRoot
-				modChain := new(ChainAcc)
-				for i, v := range chain.HashList {
+				modChain := newTestChainAcc()
+				for i, v := range chain.MD.HashList {
 					if i == j {
 						v[0] ^= 1 // Flip one bit only in the inputs into the new ChainAcc
 					}
-					modChain.AddToChain(v)
+					modChain.MD.AddToChain(v)
 				}
-				modMDRoot := modChain.GetMDRoot()
+				modMDRoot := modChain.MD.GetMDRoot()
 				if *MDRoot == *modMDRoot {
 					t.Error("We modified a bit in the inputs to modChain that was not detected in the MDRoot")
 					return
@@ -86,3 +98,81 @@ func TestMerkleInclusion(t *testing.T) {
 		}
 	}
 }
+
+func TestRunningChainMD(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	full := new(merkleDag.MD)
+	chainID := GetTestEntry(0, 0).ChainID
+
+	// Build a chain over several blocks.  The ListMDRoot of each node should cover the whole chain so far.
+	e := 0
+	for block := 0; block < 4; block++ {
+		for i := 0; i < 5+block*3; i++ {
+			entry := GetTestEntry(0, e)
+			e++
+			acc.acceptEntry(entry)
+			full.AddToChain(entry.EntryHash)
+		}
+		if result := acc.sealBlock(); result.Err != nil {
+			t.Fatal(result.Err)
+		}
+
+		headHash := acc.DB.Get(types.NodeHead, chainID[:])
+		head, err := node.GetNode(acc.DB, headHash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if head.ListMDRoot != *full.GetMDRoot() {
+			t.Errorf("block %d: the chain's ListMDRoot should cover all the entries in the chain", block)
+		}
+		if len(head.EntryList) != 5+block*3 {
+			t.Errorf("block %d: the chain node should only list the entries of its block", block)
+		}
+
+		var mdNode merkleDag.MDNode
+		if _, err := mdNode.Unmarshal(acc.DB.Get(types.MDState, headHash)); err != nil {
+			t.Fatal(err)
+		}
+		md, err := mdNode.GetMD()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *md.GetMDRoot() != head.ListMDRoot || md.Count() != full.Count() {
+			t.Errorf("block %d: the stored MD state should rebuild the chain's MD", block)
+		}
+	}
+}
+
+func TestCorruptChainState(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	for c := 0; c < 2; c++ {
+		acc.acceptEntry(GetTestEntry(c, 0))
+	}
+	if result := acc.sealBlock(); result.Err != nil {
+		t.Fatal(result.Err)
+	}
+
+	// Break the MD state of chain 0, and the head node of chain 1
+	head0 := acc.DB.Get(types.NodeHead, GetTestEntry(0, 0).ChainID.Bytes())
+	acc.DB.Put(types.MDState, head0, []byte{1, 2, 3})
+	head1 := acc.DB.Get(types.NodeHead, GetTestEntry(1, 0).ChainID.Bytes())
+	acc.DB.Put(types.Node, head1, []byte{1, 2, 3})
+
+	// Entries to the broken chains are refused rather than crashing the accumulator, and other chains go on
+	for c := 0; c < 2; c++ {
+		if err := acc.acceptEntry(GetTestEntry(c, 1)); err == nil {
+			t.Errorf("an entry to chain %d, whose state can't be read, should be refused", c)
+		}
+	}
+	if err := acc.acceptEntry(GetTestEntry(2, 1)); err != nil {
+		t.Fatal(err)
+	}
+	result := acc.sealBlock()
+	if result.Err != nil {
+		t.Fatal(result.Err)
+	}
+	if result.ChainCnt != 1 || result.EntryCnt != 1 || result.Refused != 2 {
+		t.Errorf("expected 1 entry in 1 chain and 2 refused, got %d entries in %d chains and %d refused",
+			result.EntryCnt, result.ChainCnt, result.Refused)
+	}
+}
@@ -1,8 +1,18 @@
 package accumulator
 
-import "github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
+import (
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/database"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/merkleDag"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/node"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
+)
 
-// NodeBlock
+// DefaultFanout
+// The fanout used by the router.  With 256 entries per node, a million chains in a block are covered by
+// three levels of nodes under the directory block.
+const DefaultFanout = 256
+
+// NodeBlocks
 // A Node Block collects a set of Merkle DAGs and creates a MD from that.  At the root, the NodeBlock is
 // called the Directory Block in Factom.  But as this is a much higher volume architecture, we don't limit ourselves
 // to Directory Blocks and Entry Blocks, but use a more general structure of nodes.  At the leaf level, a node
@@ -11,12 +21,52 @@ import "github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
 //
 // If the SequenceNumber is zero and the Previous Hash is nil, this is an intermediate node, and all chains are
 // equal to the given ChainID, and less than the next NodeBlock's ChainID.
-// covering particular ranges of chains
-type NodeBlock struct {
-	ChainID        *types.Hash
-	SequenceNumber int32
-	Height         uint32
-	Timestamp      uint64
-	MD             types.Hash
-	Previous       types.Hash
+//
+// BuildNodeBlocks takes the list of chains updated in a block (sorted by ChainID) and builds intermediate nodes
+// over ranges of at most fanout entries, level by level, until no more than fanout entries are left.  That
+// top level is returned to become the List of the Directory Block.  Every intermediate node is written to the
+// batch (see node.PutSubNode), so the MDRoot of each level can be proven and the nodes fetched independently.
+//
+// A fanout of zero or one leaves the list flat, and the Directory Block lists every chain directly.
+func BuildNodeBlocks(db database.Store, height types.BlockHeight, list []node.NEList, fanout int) ([]node.NEList, error) {
+	if fanout < 2 {
+		return list, nil
+	}
+	for len(list) > fanout {
+		var level []node.NEList
+		for start := 0; start < len(list); start += fanout {
+			end := start + fanout
+			if end > len(list) {
+				end = len(list)
+			}
+			subNode := NewNodeBlock(height, list[start:end])
+			if err := subNode.PutSubNode(db); err != nil {
+				return nil, err
+			}
+			ne := new(node.NEList)
+			ne.ChainID = subNode.ChainID
+			ne.MDRoot = *subNode.GetMDRoot()
+			level = append(level, *ne)
+		}
+		list = level
+	}
+	return list, nil
+}
+
+// NewNodeBlock
+// Build an intermediate node over the given range of the list.  The ChainID of the node is the first
+// ChainID in its range.
+func NewNodeBlock(height types.BlockHeight, list []node.NEList) *node.Node {
+	subNode := new(node.Node)
+	subNode.Version = types.Version
+	subNode.BHeight = height
+	subNode.ChainID = list[0].ChainID
+	subNode.IsNode = true
+	subNode.List = append(subNode.List, list...)
+	md := new(merkleDag.MD)
+	for _, ne := range list {
+		md.AddToChain(ne.MDRoot)
+	}
+	subNode.ListMDRoot = *md.GetMDRoot()
+	return subNode
 }
@@ -0,0 +1,84 @@
+package accumulator
+
+import (
+	"testing"
+
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/merkleDag"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/node"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
+)
+
+func TestNodeBlocks(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	acc.Fanout = 3
+	for c := 0; c < 20; c++ {
+		acc.acceptEntry(GetTestEntry(c, 0))
+	}
+	if result := acc.sealBlock(); result.Err != nil {
+		t.Fatal(result.Err)
+	}
+
+	directoryBlock, err := node.GetDirectoryBlock(acc.DB, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(directoryBlock.List) > acc.Fanout {
+		t.Errorf("the directory block should list at most %d nodes, got %d", acc.Fanout, len(directoryBlock.List))
+	}
+
+	// Walk down the tree, checking every intermediate node covers its range, and proves its List
+	var walk func(list []node.NEList, depth int) int
+	walk = func(list []node.NEList, depth int) (leaves int) {
+		for i, ne := range list {
+			n, err := node.GetNodeByMDRoot(acc.DB, ne.MDRoot)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *n.GetMDRoot() != ne.MDRoot || n.ChainID != ne.ChainID {
+				t.Fatal("the node found should match the NEList")
+			}
+			if !n.IsNode {
+				leaves++
+				continue
+			}
+			if n.SequenceNum != 0 || n.BHeight != 0 || len(n.List) > acc.Fanout || n.List[0].ChainID != n.ChainID {
+				t.Errorf("bad intermediate node %d at depth %d", i, depth)
+			}
+			md := new(merkleDag.MD)
+			for _, sub := range n.List {
+				md.AddToChain(sub.MDRoot)
+			}
+			if *md.GetMDRoot() != n.ListMDRoot {
+				t.Error("the ListMDRoot of an intermediate node should be the MD of its List")
+			}
+			leaves += walk(n.List, depth+1)
+		}
+		return leaves
+	}
+	if leaves := walk(directoryBlock.List, 0); leaves != 20 {
+		t.Errorf("expected 20 chain nodes under the directory block, found %d", leaves)
+	}
+
+	chains, err := node.GetChainsAtHeight(acc.DB, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chains) != 20 {
+		t.Fatalf("expected 20 chains at height 0, got %d", len(chains))
+	}
+	for i := 1; i < len(chains); i++ {
+		if string(chains[i-1].ChainID[:]) >= string(chains[i].ChainID[:]) {
+			t.Error("the chains should be in ChainID order")
+		}
+	}
+
+	// Intermediate nodes are not part of any chain, so the chain heads still point at the chain nodes
+	chainID := GetTestEntry(0, 0).ChainID
+	head, err := node.GetNode(acc.DB, acc.DB.Get(types.NodeHead, chainID[:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.IsNode {
+		t.Error("the head of a chain should be its chain node")
+	}
+}
@@ -0,0 +1,171 @@
+package accumulator
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/database"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/node"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
+)
+
+// ChainMove
+// A move of a chain between accumulators.  The chain's nodes up to Sequence (and Height) were written by the
+// accumulator whose directory blocks have the ChainID Accumulator, and only that accumulator's directory
+// blocks list them, so receipts for the entries in those nodes are built from that accumulator's database.
+type ChainMove struct {
+	Accumulator types.Hash        // ChainID of the directory blocks of the accumulator moved from
+	Height      types.BlockHeight // The last height the chain was written at by that accumulator
+	Sequence    types.Sequence    // The SequenceNum of the last node of the chain written by that accumulator
+}
+
+// GetChainMoves
+// Returns the moves that brought a chain to the accumulator of the database, oldest first.  A chain that
+// never moved has none.
+func GetChainMoves(db *database.DB, chainID types.Hash) (moves []ChainMove, err error) {
+	data := db.Get(types.ChainMoves, chainID[:])
+	if data == nil {
+		return nil, nil
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New(fmt.Sprintf("ChainMoves Failed to unmarshal %v", r))
+		}
+	}()
+	var count, height, sequence uint32
+	count, data = types.BytesUint32(data)
+	for i := uint32(0); i < count; i++ {
+		var move ChainMove
+		data = move.Accumulator.Extract(data)
+		height, data = types.BytesUint32(data)
+		sequence, data = types.BytesUint32(data)
+		move.Height = types.BlockHeight(height)
+		move.Sequence = types.Sequence(sequence)
+		moves = append(moves, move)
+	}
+	return moves, nil
+}
+
+// marshalChainMoves
+// The encoding of the moves of a chain in the ChainMoves bucket
+func marshalChainMoves(moves []ChainMove) (data []byte) {
+	data = append(data, types.Uint32Bytes(uint32(len(moves)))...)
+	for _, move := range moves {
+		data = append(data, move.Accumulator.Bytes()...)
+		data = append(data, types.Uint32Bytes(uint32(move.Height))...)
+		data = append(data, types.Uint32Bytes(uint32(move.Sequence))...)
+	}
+	return data
+}
+
+// MovedFrom
+// Returns the move that took a chain off the accumulator that wrote the chain's node with the given
+// SequenceNum, or nil if the node was written after the last move.
+func MovedFrom(db *database.DB, chainID types.Hash, sequence types.Sequence) (*ChainMove, error) {
+	moves, err := GetChainMoves(db, chainID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range moves {
+		if sequence <= moves[i].Sequence {
+			return &moves[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// MigrateChain
+// Move a chain from the database of one accumulator to the database of another.  Every node of the chain is
+// copied to the destination with its MD state and indexes, and the destination takes over the chain's head.
+// The next node of the chain is then built by the destination, following on from the last node written by
+// the source, so the Previous and SequenceNum links and the chain's MD carry on across the move.
+//
+// The source keeps the chain's history, its nodes and the first and next indexes, as its directory blocks
+// still list the nodes and receipts for their entries are built from its database.  It only gives up the
+// head, so it no longer owns the chain.  The destination records the move (see GetChainMoves), so it can
+// tell which accumulator holds the receipts for the entries written before the move.
+//
+// Must only be called at a block boundary, when neither accumulator is running and the chain has no
+// entries in a block in flight.
+func MigrateChain(src, dst *database.DB, chainID types.Hash) error {
+	srcHead := src.Get(types.NodeHead, chainID[:])
+	first := src.Get(types.NodeFirst, chainID[:])
+	if srcHead == nil || first == nil {
+		return errors.New(fmt.Sprintf("chain %x is not in the source database", chainID))
+	}
+	dstHead := dst.Get(types.NodeHead, chainID[:])
+	if dstHead != nil && !bytes.Equal(dstHead, srcHead) {
+		return errors.New(fmt.Sprintf("chain %x is already in the destination database", chainID))
+	}
+	copied := dstHead != nil // A move that copied the chain, but failed to remove it from the source
+
+	copyBatch := dst.NewBatch()
+	defer copyBatch.Close()
+
+	var head []byte
+	var headNode *node.Node
+	for nodeHash := first; nodeHash != nil; nodeHash = src.Get(types.NodeNext, nodeHash) {
+		n, err := node.GetNode(src, nodeHash)
+		if err != nil {
+			return err
+		}
+		if n.ChainID != chainID {
+			return errors.New(fmt.Sprintf("node %x in the history of chain %x is for chain %x", nodeHash, chainID, n.ChainID))
+		}
+		if err := n.PutSubNode(copyBatch); err != nil { // The node, and its MDRoot index
+			return err
+		}
+		if mdState := src.Get(types.MDState, nodeHash); mdState != nil {
+			if err := copyBatch.Put(types.MDState, nodeHash, mdState); err != nil {
+				return err
+			}
+		}
+		for _, entryHash := range n.EntryList {
+			if err := copyBatch.Put(types.EntryNode, entryHash.Bytes(), nodeHash); err != nil {
+				return err
+			}
+		}
+		if head != nil {
+			if err := copyBatch.Put(types.NodeNext, head, nodeHash); err != nil {
+				return err
+			}
+		}
+		head, headNode = nodeHash, n
+	}
+	if !bytes.Equal(head, srcHead) {
+		return errors.New(fmt.Sprintf("the history of chain %x does not end at its head", chainID))
+	}
+	if err := copyBatch.Put(types.NodeFirst, chainID[:], first); err != nil {
+		return err
+	}
+	if err := copyBatch.Put(types.NodeHead, chainID[:], head); err != nil {
+		return err
+	}
+
+	// Record the move, after any moves that brought the chain to the source.  If the source wrote nothing to
+	// the chain since it arrived, the moves already say where all of the chain's history is.
+	moves, err := GetChainMoves(src, chainID)
+	if err != nil {
+		return err
+	}
+	if len(moves) == 0 || moves[len(moves)-1].Sequence < headNode.SequenceNum {
+		directoryBlock, err := node.GetDirectoryBlock(src, headNode.BHeight)
+		if err != nil {
+			return err
+		}
+		moves = append(moves, ChainMove{Accumulator: directoryBlock.ChainID, Height: headNode.BHeight, Sequence: headNode.SequenceNum})
+	}
+	if err := copyBatch.Put(types.ChainMoves, chainID[:], marshalChainMoves(moves)); err != nil {
+		return err
+	}
+
+	// The copy is written first.  If removing the head from the source then fails, the chain is in both
+	// databases with the same head, and a retry of the move just finishes removing it from the source.
+	if !copied {
+		if err := copyBatch.Write(); err != nil {
+			return err
+		}
+	}
+	return src.Delete(types.NodeHead, chainID[:])
+}
@@ -0,0 +1,177 @@
+package accumulator
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/database"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/merkleDag"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/node"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
+	dbm "github.com/tendermint/tm-db"
+)
+
+func TestMigrateChain(t *testing.T) {
+	src := GetTestAccumulator(t)
+	dst := new(Accumulator)
+	dstDB := new(database.DB)
+	dstDB.InitDB(dbm.NewMemDB())
+	dstID := types.Hash(sha256.Sum256([]byte("TestAcc 2 DID")))
+	dst.Init(dstDB, &dstID)
+
+	chainID := GetTestEntry(1, 0).ChainID
+	chainMD := new(merkleDag.MD) // The MD of every entry in the chain
+	for block := 0; block < 2; block++ {
+		for c := 0; c < 3; c++ {
+			for e := 0; e < 3; e++ {
+				src.acceptEntry(GetTestEntry(c, block*3+e))
+				if c == 1 {
+					chainMD.AddToChain(GetTestEntry(c, block*3+e).EntryHash)
+				}
+			}
+		}
+		if result := src.sealBlock(); result.Err != nil {
+			t.Fatal(result.Err)
+		}
+	}
+	dst.acceptEntry(GetTestEntry(5, 0))
+	if result := dst.sealBlock(); result.Err != nil {
+		t.Fatal(result.Err)
+	}
+	oldHead := src.DB.Get(types.NodeHead, chainID[:])
+
+	if err := MigrateChain(src.DB, dst.DB, chainID); err != nil {
+		t.Fatal(err)
+	}
+	if err := MigrateChain(src.DB, dst.DB, chainID); err == nil {
+		t.Error("a chain can't be moved from a database that no longer holds it")
+	}
+	srcChains, _ := src.Chains()
+	dstChains, _ := dst.Chains()
+	if len(srcChains) != 2 || len(dstChains) != 2 {
+		t.Errorf("expected 2 chains in each accumulator, got %d and %d", len(srcChains), len(dstChains))
+	}
+
+	// The destination carries on the chain
+	dst.acceptEntry(GetTestEntry(1, 3)) // Recorded by the source already
+	for e := 6; e < 9; e++ {
+		dst.acceptEntry(GetTestEntry(1, e))
+		chainMD.AddToChain(GetTestEntry(1, e).EntryHash)
+	}
+	result := dst.sealBlock()
+	if result.Err != nil {
+		t.Fatal(result.Err)
+	}
+	if result.EntryCnt != 3 {
+		t.Errorf("entries recorded before the move should be rejected as duplicates; expected 3 entries, got %d", result.EntryCnt)
+	}
+	head, err := node.GetNode(dst.DB, dst.DB.Get(types.NodeHead, chainID[:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.SequenceNum != 2 || !bytes.Equal(head.Previous[:], oldHead) {
+		t.Error("the first node written by the destination should follow the last node written by the source")
+	}
+	if head.ListMDRoot != *chainMD.GetMDRoot() {
+		t.Error("the chain's MD should carry on across the move")
+	}
+
+	// Walk the whole chain in the destination, checking the links
+	var previous []byte
+	sequence := types.Sequence(0)
+	for nodeHash := dst.DB.Get(types.NodeFirst, chainID[:]); nodeHash != nil; nodeHash = dst.DB.Get(types.NodeNext, nodeHash) {
+		n, err := node.GetNode(dst.DB, nodeHash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n.SequenceNum != sequence || (previous != nil && !bytes.Equal(n.Previous[:], previous)) {
+			t.Errorf("node %d of the chain is not linked to the node before it", sequence)
+		}
+		previous = nodeHash
+		sequence++
+	}
+	if sequence != 3 {
+		t.Errorf("expected 3 nodes in the chain, found %d", sequence)
+	}
+
+	// The destination knows the history before the move is in the source, which keeps it
+	moves, err := GetChainMoves(dst.DB, chainID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(moves) != 1 || moves[0].Accumulator != *src.chainID || moves[0].Height != 1 || moves[0].Sequence != 1 {
+		t.Fatalf("expected the move from the source after node 1 at height 1 to be recorded, got %v", moves)
+	}
+	walked := 0
+	for nodeHash := src.DB.Get(types.NodeFirst, chainID[:]); nodeHash != nil; nodeHash = src.DB.Get(types.NodeNext, nodeHash) {
+		walked++
+	}
+	if walked != 2 {
+		t.Errorf("the source should keep the 2 nodes of the chain's history it wrote, walked %d", walked)
+	}
+
+	// Receipts for the entries recorded before the move come from the source, up to its directory blocks
+	for _, e := range []int{0, 5} {
+		entryHash := GetTestEntry(1, e).EntryHash
+		cr, err := BuildCompositeReceiptFromDB(src.DB, entryHash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		directoryBlock, err := node.GetDirectoryBlock(src.DB, types.BlockHeight(e/3))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !cr.Validate() || cr.EntryHash != entryHash || cr.MDRoot != *directoryBlock.GetMDRoot() {
+			t.Errorf("the receipt for entry %d from the source should prove it against the source's directory block", e)
+		}
+		if _, err := BuildCompositeReceiptFromDB(dst.DB, entryHash); err == nil {
+			t.Errorf("the destination can't prove entry %d, recorded before the move, and should say so", e)
+		}
+	}
+	cr, err := BuildCompositeReceiptFromDB(dst.DB, GetTestEntry(1, 7).EntryHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cr.Validate() || cr.MDRoot != result.MDRoot {
+		t.Error("the receipt for an entry recorded after the move should validate")
+	}
+
+	// Moving the chain back, the source proves its own history again, and the destination what it wrote
+	if err := MigrateChain(dst.DB, src.DB, chainID); err != nil {
+		t.Fatal(err)
+	}
+	if moves, _ := GetChainMoves(src.DB, chainID); len(moves) != 2 || moves[1].Accumulator != dstID {
+		t.Errorf("expected both moves recorded on the way back, got %v", moves)
+	}
+	if cr, err := BuildCompositeReceiptFromDB(src.DB, GetTestEntry(1, 0).EntryHash); err != nil || !cr.Validate() {
+		t.Errorf("the source should prove the entries it recorded after the chain moves back: %v", err)
+	}
+	if _, err := BuildCompositeReceiptFromDB(src.DB, GetTestEntry(1, 7).EntryHash); err == nil {
+		t.Error("the entries recorded by the destination can't be proven by the source")
+	}
+}
+
+func TestAlignHeight(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	if err := acc.AlignHeight(5); err != nil {
+		t.Fatal(err)
+	}
+	acc.acceptEntry(GetTestEntry(0, 0))
+	if err := acc.AlignHeight(6); err == nil {
+		t.Error("should not move to another height with a block in flight")
+	}
+	result := acc.SealPending()
+	if result == nil || result.Err != nil || result.Height != 5 {
+		t.Fatal("the block in flight should be sealed at height 5")
+	}
+	if acc.SealPending() != nil {
+		t.Error("there is nothing in flight to seal")
+	}
+	if err := acc.AlignHeight(3); err == nil {
+		t.Error("should not move back to an earlier height")
+	}
+	if _, err := node.GetDirectoryBlock(acc.DB, 5); err != nil {
+		t.Error("the directory block should be at the aligned height")
+	}
+}
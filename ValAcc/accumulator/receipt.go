@@ -0,0 +1,127 @@
+package accumulator
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/database"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/merkleDag"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/node"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
+)
+
+// BuildCompositeReceipt
+// Build a receipt proving the entry hash up to the MDRoot of a directory block.  The chainMD is the MD of the
+// chain the entry was added to, as it stood when the chain node was sealed.  The path is the chain node holding
+// the entry, followed by each node above it, ending with the directory block.  Returns an error if the entry
+// is not in the chainMD, or if any node in the path does not list the node below it.
+func BuildCompositeReceipt(entryHash types.Hash, chainMD merkleDag.MD, path []*node.Node) (*merkleDag.CompositeReceipt, error) {
+	if len(path) == 0 {
+		return nil, errors.New("no nodes given to build a receipt")
+	}
+	cr := new(merkleDag.CompositeReceipt)
+	cr.EntryHash = entryHash
+
+	md := chainMD // The first level proves the entry against the chain's MD
+	hash := entryHash
+	for i, n := range path {
+		level := new(merkleDag.ReceiptLevel)
+		level.Receipt.BuildMDReceipt(md, hash)
+		if !level.Receipt.Validate() {
+			return nil, errors.New(fmt.Sprintf("%x not found in the list of node %d of the path", hash, i))
+		}
+		if level.Receipt.MDRoot != n.ListMDRoot {
+			return nil, errors.New(fmt.Sprintf("the receipt for node %d of the path does not match its ListMDRoot", i))
+		}
+		level.HeaderHash = *n.GetHash()
+		cr.Levels = append(cr.Levels, level)
+
+		// The next level proves the MDRoot of this node against the List of the node above
+		hash = *n.GetMDRoot()
+		md = merkleDag.MD{}
+		if i+1 < len(path) {
+			for _, ne := range path[i+1].List {
+				md.AddToChain(ne.MDRoot)
+			}
+		}
+	}
+	cr.MDRoot = hash
+	return cr, nil
+}
+
+// GetChainMD
+// Rebuild the MD of a chain as it stood when the given chain node was sealed.  The MD is restored from the
+// MD state stored with the node, so the HashList holds the entries of just that node.  A node without a
+// stored MD state is rebuilt from its EntryList alone.
+func GetChainMD(db *database.DB, nodeHash types.Hash, n *node.Node) (*merkleDag.MD, error) {
+	mdBytes := db.Get(types.MDState, nodeHash.Bytes())
+	if mdBytes == nil {
+		md := new(merkleDag.MD)
+		for _, h := range n.EntryList {
+			md.AddToChain(h)
+		}
+		return md, nil
+	}
+	var mdNode merkleDag.MDNode
+	if _, err := mdNode.Unmarshal(mdBytes); err != nil {
+		return nil, err
+	}
+	return mdNode.GetMD()
+}
+
+// BuildReceiptFromDB
+// Build a receipt for an entry recorded in a sealed block, using nothing but the database.  The receipt
+// proves the entry against the ListMDRoot of the chain node holding it.  Returns the receipt and the chain
+// node, or an error if the entry is not found or the receipt doesn't match the node.
+func BuildReceiptFromDB(db *database.DB, entryHash types.Hash) (*merkleDag.MDReceipt, *node.Node, error) {
+	loc, err := node.GetEntryLocation(db, entryHash)
+	if err != nil {
+		return nil, nil, err
+	}
+	md, err := GetChainMD(db, loc.NodeHash, loc.Node)
+	if err != nil {
+		return nil, nil, err
+	}
+	receipt := new(merkleDag.MDReceipt)
+	receipt.BuildMDReceipt(*md, entryHash)
+	if !receipt.Validate() || receipt.MDRoot != loc.Node.ListMDRoot {
+		return nil, nil, errors.New(fmt.Sprintf("the receipt for entry %x does not match node %x", entryHash, loc.NodeHash))
+	}
+	return receipt, loc.Node, nil
+}
+
+// BuildCompositeReceiptFromDB
+// Build a receipt proving an entry recorded in a sealed block up to the MDRoot of that block's directory
+// block, using nothing but the database.  An entry of a chain moved onto this accumulator, recorded before
+// the move, is only listed in the directory blocks of the accumulator it was moved from (see ChainMove), so
+// its receipt must be built from that accumulator's database; here it is an error naming the accumulator.
+func BuildCompositeReceiptFromDB(db *database.DB, entryHash types.Hash) (*merkleDag.CompositeReceipt, error) {
+	loc, err := node.GetEntryLocation(db, entryHash)
+	if err != nil {
+		return nil, err
+	}
+	md, err := GetChainMD(db, loc.NodeHash, loc.Node)
+	if err != nil {
+		return nil, err
+	}
+	move, err := MovedFrom(db, loc.Node.ChainID, loc.Node.SequenceNum)
+	if err != nil {
+		return nil, err
+	}
+	if move != nil { // Unless the chain has since moved back, the node is listed in another accumulator
+		directoryBlock, err := node.GetDirectoryBlock(db, loc.BHeight)
+		if err != nil || directoryBlock.ChainID != move.Accumulator {
+			return nil, errors.New(fmt.Sprintf("entry %x was recorded at height %d by accumulator %x, before chain %x moved; "+
+				"its receipt is built from the database of that accumulator", entryHash, loc.BHeight, move.Accumulator, loc.Node.ChainID))
+		}
+	}
+	path, err := node.GetNodePath(db, loc.BHeight, loc.Node.ChainID)
+	if err != nil {
+		return nil, err
+	}
+	if *path[0].GetHash() != loc.NodeHash {
+		return nil, errors.New(fmt.Sprintf("node %x holding entry %x is not listed at height %d",
+			loc.NodeHash, entryHash, loc.BHeight))
+	}
+	return BuildCompositeReceipt(entryHash, *md, path)
+}
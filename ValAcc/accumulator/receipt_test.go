@@ -0,0 +1,128 @@
+package accumulator
+
+import (
+	"testing"
+
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/node"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
+)
+
+func TestCompositeReceipt(t *testing.T) {
+	for _, fanout := range []int{0, 3} {
+		acc := GetTestAccumulator(t)
+		acc.Fanout = fanout
+		for c := 0; c < 10; c++ {
+			for e := 0; e < 4; e++ {
+				acc.acceptEntry(GetTestEntry(c, e))
+			}
+		}
+		if result := acc.sealBlock(); result.Err != nil {
+			t.Fatal(result.Err)
+		}
+
+		// The second block continues the chain's MD, so the receipt covers the entries of both blocks
+		for c := 0; c < 10; c++ {
+			acc.acceptEntry(GetTestEntry(c, 4))
+		}
+		for e := 5; e < 7; e++ {
+			acc.acceptEntry(GetTestEntry(5, e))
+		}
+		chainID := GetTestEntry(5, 0).ChainID
+		chain := acc.chains[chainID]
+		result := acc.sealBlock()
+		if result.Err != nil {
+			t.Fatal(result.Err)
+		}
+
+		path, err := node.GetNodePath(acc.DB, 1, chainID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := map[int]int{0: 2, 3: 4}[fanout]; len(path) != expected {
+			t.Errorf("fanout %d should give a path of %d nodes, got %d", fanout, expected, len(path))
+		}
+		for e := 4; e < 7; e++ {
+			entry := GetTestEntry(5, e).EntryHash
+			cr, err := BuildCompositeReceipt(entry, *chain.MD, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !cr.Validate() {
+				t.Errorf("fanout %d: the receipt for entry %d should validate", fanout, e)
+			}
+			if cr.MDRoot != result.MDRoot {
+				t.Errorf("fanout %d: the receipt should end at the MDRoot of the block", fanout)
+			}
+
+			// Any change to the receipt should fail
+			cr.Levels[len(cr.Levels)-1].HeaderHash[0]++
+			if cr.Validate() {
+				t.Error("a receipt with a bad header hash should not validate")
+			}
+			cr.Levels[len(cr.Levels)-1].HeaderHash[0]--
+			cr.Levels[0].Receipt.EntryHash[0]++
+			if cr.Validate() {
+				t.Error("a receipt with a bad entry hash should not validate")
+			}
+		}
+
+		if _, err := BuildCompositeReceipt(GetTestEntry(5, 0).EntryHash, *chain.MD, path); err == nil {
+			t.Error("an entry from a previous block is not in this block of the chain's MD")
+		}
+		if _, err := BuildCompositeReceipt(GetTestEntry(5, 4).EntryHash, *chain.MD, path[1:]); err == nil {
+			t.Error("a path that doesn't start with the chain node should fail")
+		}
+	}
+}
+
+func TestReceiptFromDB(t *testing.T) {
+	acc := GetTestAccumulator(t)
+	acc.Fanout = 3
+	for block := 0; block < 3; block++ {
+		for c := 0; c < 10; c++ {
+			for e := 0; e < 3; e++ {
+				acc.acceptEntry(GetTestEntry(c, block*3+e))
+			}
+		}
+		if result := acc.sealBlock(); result.Err != nil {
+			t.Fatal(result.Err)
+		}
+	}
+
+	for block := 0; block < 3; block++ {
+		directoryBlock, err := node.GetDirectoryBlock(acc.DB, types.BlockHeight(block))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for c := 0; c < 10; c++ {
+			for e := 0; e < 3; e++ {
+				entry := GetTestEntry(c, block*3+e).EntryHash
+				receipt, chainNode, err := BuildReceiptFromDB(acc.DB, entry)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !receipt.Validate() || receipt.MDRoot != chainNode.ListMDRoot {
+					t.Errorf("the receipt for chain %d entry %d should prove the chain node", c, block*3+e)
+				}
+				if chainNode.BHeight != types.BlockHeight(block) {
+					t.Errorf("chain %d entry %d should be found at height %d", c, block*3+e, block)
+				}
+
+				cr, err := BuildCompositeReceiptFromDB(acc.DB, entry)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !cr.Validate() || cr.MDRoot != *directoryBlock.GetMDRoot() {
+					t.Errorf("the composite receipt for chain %d entry %d should prove the directory block", c, block*3+e)
+				}
+			}
+		}
+	}
+
+	if _, _, err := BuildReceiptFromDB(acc.DB, GetTestEntry(0, 100).EntryHash); err == nil {
+		t.Error("there is no receipt for an entry never recorded")
+	}
+	if _, err := BuildCompositeReceiptFromDB(acc.DB, GetTestEntry(0, 100).EntryHash); err == nil {
+		t.Error("there is no composite receipt for an entry never recorded")
+	}
+}
@@ -0,0 +1,102 @@
+package accumulator
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/node"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
+)
+
+// WAL
+// Write-ahead log of the entries accepted by an Accumulator into the block in flight.  Entries only live in
+// memory until their block is sealed, so every accepted EntryHash is also appended to the log.  If the
+// process dies, the log is replayed on restart to rebuild the block in flight.  The log is reset every
+// time a block is sealed.
+//
+// Each record is
+//
+//	len(record)     uint32
+//	BlockHeight     uint32
+//	EntryHash       node.EntryHash.Marshal()
+//
+// Records are written straight to the file without buffering, so they survive a crash of the process.
+type WAL struct {
+	path string   // Path to the log file
+	file *os.File // Log file, opened for append
+}
+
+// OpenWAL
+// Open the write-ahead log at the given path, creating it if it does not exist.
+func OpenWAL(path string) (*WAL, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	w := new(WAL)
+	w.path = path
+	w.file = file
+	return w, nil
+}
+
+// Append
+// Record an entry accepted into the block at the given height.
+func (w *WAL) Append(height types.BlockHeight, entry node.EntryHash) error {
+	record := height.Bytes()
+	record = append(record, entry.Marshal()...)
+	_, err := w.file.Write(append(types.Uint32Bytes(uint32(len(record))), record...))
+	return err
+}
+
+// Replay
+// Call fn with every record in the log, in the order they were appended.  A partial record at the end
+// of the log (a write cut off by a crash) is cut off the file, so the records appended after the replay
+// follow the last complete record.  Returns the length of the log up to the end of that record.
+func (w *WAL) Replay(fn func(height types.BlockHeight, entry node.EntryHash)) (good int64, err error) {
+	data, err := ioutil.ReadFile(w.path)
+	if err != nil {
+		return 0, err
+	}
+	size := int64(len(data))
+	for len(data) >= 4 {
+		var recordLen uint32
+		recordLen, data = types.BytesUint32(data)
+		if uint32(len(data)) < recordLen { // The last record was never completely written
+			break
+		}
+		record := data[:recordLen]
+		data = data[recordLen:]
+
+		if len(record) < 4 {
+			return good, errors.New(fmt.Sprintf("write-ahead log record of %d bytes is too short", len(record)))
+		}
+		var height types.BlockHeight
+		var entry node.EntryHash
+		record = height.Extract(record)
+		if _, err := entry.Unmarshal(record); err != nil {
+			return good, err
+		}
+		fn(height, entry)
+		good += 4 + int64(recordLen)
+	}
+	if good < size {
+		if err := w.file.Truncate(good); err != nil {
+			return good, errors.New(fmt.Sprintf("failed to cut the partial record off the write-ahead log: %v", err))
+		}
+	}
+	return good, nil
+}
+
+// Reset
+// Throw away all the records in the log.  Called once the block in flight has been sealed.
+func (w *WAL) Reset() error {
+	return w.file.Truncate(0)
+}
+
+// Close
+// Close the log file.
+func (w *WAL) Close() error {
+	return w.file.Close()
+}
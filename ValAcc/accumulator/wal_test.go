@@ -0,0 +1,216 @@
+package accumulator
+
+import (
+	"context"
+	"crypto/sha256"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/database"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/node"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
+	dbm "github.com/tendermint/tm-db"
+)
+
+func TestWAL(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.wal")
+
+	wal, err := OpenWAL(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var written []node.EntryHash
+	for i := 0; i < 10; i++ {
+		entry := GetTestEntry(i%3, i)
+		entry.SubChains = []types.Hash{sha256.Sum256([]byte("sub chain"))}
+		written = append(written, entry)
+		if err := wal.Append(types.BlockHeight(i/5), entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wal.file.Write([]byte{0, 0, 0, 100, 1, 2, 3}) // A record cut off by a crash
+
+	var read []node.EntryHash
+	_, err = wal.Replay(func(height types.BlockHeight, entry node.EntryHash) {
+		if int(height) != len(read)/5 {
+			t.Errorf("record %d should be at height %d, got %d", len(read), len(read)/5, height)
+		}
+		read = append(read, entry)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(read) != len(written) {
+		t.Fatalf("expected %d records, replayed %d", len(written), len(read))
+	}
+	for i := range read {
+		if read[i].ChainID != written[i].ChainID || read[i].EntryHash != written[i].EntryHash ||
+			len(read[i].SubChains) != 1 || read[i].SubChains[0] != written[i].SubChains[0] {
+			t.Errorf("record %d did not replay as written", i)
+		}
+	}
+
+	if err := wal.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	wal.Append(7, written[0])
+	read = read[:0]
+	wal.Replay(func(height types.BlockHeight, entry node.EntryHash) { read = append(read, entry) })
+	if len(read) != 1 {
+		t.Errorf("expected only the record written after the reset, got %d records", len(read))
+	}
+	wal.Close()
+}
+
+func TestWALTornTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.wal")
+	wal, err := OpenWAL(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		wal.Append(0, GetTestEntry(0, i))
+	}
+	wal.file.Write([]byte{0, 0, 0, 100, 1, 2, 3}) // A record cut off by a crash
+	wal.Close()
+
+	// Restart: replay, then append more without a reset, as the accumulator does
+	if wal, err = OpenWAL(path); err != nil {
+		t.Fatal(err)
+	}
+	good, err := wal.Replay(func(types.BlockHeight, node.EntryHash) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := os.Stat(path); info.Size() != good {
+		t.Errorf("the partial record should be cut off the log, leaving %d bytes, not %d", good, info.Size())
+	}
+	for i := 3; i < 5; i++ {
+		if err := wal.Append(0, GetTestEntry(0, i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wal.Close()
+
+	// Restart again, and every complete record is there, in order
+	if wal, err = OpenWAL(path); err != nil {
+		t.Fatal(err)
+	}
+	defer wal.Close()
+	var read []node.EntryHash
+	if _, err := wal.Replay(func(height types.BlockHeight, entry node.EntryHash) { read = append(read, entry) }); err != nil {
+		t.Fatal(err)
+	}
+	if len(read) != 5 {
+		t.Fatalf("expected the 5 complete records, replayed %d", len(read))
+	}
+	for i, entry := range read {
+		if entry.EntryHash != GetTestEntry(0, i).EntryHash {
+			t.Errorf("record %d did not replay as written", i)
+		}
+	}
+}
+
+func TestWALFailure(t *testing.T) {
+	db := new(database.DB)
+	db.InitDB(dbm.NewMemDB())
+	chainID := types.Hash(sha256.Sum256([]byte("TestAcc DID")))
+	acc := new(Accumulator)
+	acc.WALPath = filepath.Join(t.TempDir(), "acc.wal")
+	acc.Init(db, &chainID)
+
+	if err := acc.acceptEntry(GetTestEntry(0, 0)); err != nil {
+		t.Fatal(err)
+	}
+	acc.wal.file.Close() // Every write to the log fails from here on
+	if err := acc.acceptEntry(GetTestEntry(0, 1)); err == nil {
+		t.Error("an entry that can't be written to the write-ahead log should be refused")
+	}
+	if err := acc.acceptEntry(GetTestEntry(0, 0)); err != nil {
+		t.Error("a duplicate is dropped without being logged, so is not refused")
+	}
+
+	result := acc.sealBlock()
+	if result.Err != nil {
+		t.Fatal(result.Err)
+	}
+	if result.EntryCnt != 1 || result.Refused != 1 {
+		t.Errorf("expected 1 entry recorded and 1 refused, got %d recorded and %d refused", result.EntryCnt, result.Refused)
+	}
+	if has, _ := db.Has(types.EntryNode, GetTestEntry(0, 1).EntryHash.Bytes()); has {
+		t.Error("the refused entry should not be in the block")
+	}
+}
+
+func TestWALRecovery(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	memDB := dbm.NewMemDB()
+	chainID := types.Hash(sha256.Sum256([]byte("TestAcc DID")))
+	newAcc := func() *Accumulator {
+		db := new(database.DB)
+		db.InitDB(memDB)
+		acc := new(Accumulator)
+		acc.WALPath = filepath.Join(dir, "acc.wal")
+		acc.Init(db, &chainID)
+		return acc
+	}
+
+	// Seal one block, then accept some entries into the next and "crash" without sealing
+	acc := newAcc()
+	acc.acceptEntry(GetTestEntry(0, 0))
+	if result := acc.sealBlock(); result.Err != nil {
+		t.Fatal(result.Err)
+	}
+	for c := 0; c < 3; c++ {
+		for e := 1; e < 4; e++ {
+			acc.acceptEntry(GetTestEntry(c, e))
+		}
+	}
+	acc.acceptEntry(GetTestEntry(1, 1)) // Duplicates are not logged
+	expected := map[types.Hash]types.Hash{}
+	for id, chain := range acc.chains {
+		expected[id] = *chain.MD.GetMDRoot()
+	}
+	acc.wal.Close()
+
+	// Restart, and the block in flight should be exactly as it was
+	acc2 := newAcc()
+	if acc2.height != 1 {
+		t.Fatalf("expected to restart at height 1, got %d", acc2.height)
+	}
+	if !acc2.Pending() {
+		t.Error("the block replayed from the write-ahead log should be pending")
+	}
+	if len(acc2.chains) != len(expected) {
+		t.Fatalf("expected %d chains in the block in flight, got %d", len(expected), len(acc2.chains))
+	}
+	for id, mdRoot := range expected {
+		if acc2.chains[id] == nil || *acc2.chains[id].MD.GetMDRoot() != mdRoot {
+			t.Errorf("chain %x was not rebuilt from the write-ahead log", id)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	go acc2.Run(ctx)
+	result, err := acc2.EndBlock(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Height != 1 || result.EntryCnt != 9 {
+		t.Errorf("expected 9 entries at height 1, got %d at height %d", result.EntryCnt, result.Height)
+	}
+}
@@ -0,0 +1,112 @@
+package anchor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/database"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/merkleDag"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
+)
+
+// Anchoring
+// The roots recorded by the accumulators are only as good as the proof that they existed at a given time.  So
+// the roots are published ("anchored") to some external blockchain, Factom being the one intended.  The router
+// combines the directory block roots of all its accumulators at a height into one global root, and it is the
+// global roots that are anchored.  Anchoring every global root would be slow and expensive, so the Scheduler
+// collects them into an MD, and anchors just the root of that MD.  A Record is kept for every global root,
+// holding the reference to the external transaction and a receipt from the global root to the value anchored.
+// A directory block root is proven anchored by its receipt to the global root of its height (see
+// router.GetAnchorProof), followed by the Record.
+
+// Anchorer
+// Publishes a hash to an external system.  Returns a reference to the transaction holding the hash, with
+// which the anchor can be found and checked in the external system.
+type Anchorer interface {
+	Anchor(ctx context.Context, hash types.Hash) (txRef string, err error)
+}
+
+// RecordVersion
+// Version of the binary encoding of a Record
+const RecordVersion = 1
+
+// Record
+// Proof that a global root was anchored.  The Receipt proves the GlobalRoot against the AnchoredRoot,
+// which was published in the transaction TxRef.
+type Record struct {
+	GlobalRoot   types.Hash          // The global root anchored (see router.GlobalRoot)
+	AnchoredRoot types.Hash          // The hash published; the MDRoot of a batch of global roots
+	TxRef        string              // Reference to the external transaction publishing the AnchoredRoot
+	TimeStamp    types.TimeStamp     // When the AnchoredRoot was published
+	Receipt      merkleDag.MDReceipt // Proof of the GlobalRoot against the AnchoredRoot
+}
+
+// Validate
+// Check the Receipt proves the GlobalRoot against the AnchoredRoot.  Whether the AnchoredRoot really is in the
+// TxRef can only be checked with the external system.
+func (r *Record) Validate() bool {
+	return r.Receipt.EntryHash == r.GlobalRoot && r.Receipt.MDRoot == r.AnchoredRoot && r.Receipt.Validate()
+}
+
+// Marshal
+// The binary encoding of a Record
+//
+//	version      byte      RecordVersion
+//	GlobalRoot   [32]byte
+//	AnchoredRoot [32]byte
+//	TxRef        uint16 length, then the bytes
+//	TimeStamp    TimeStamp
+//	Receipt      MDReceipt.Marshal()
+func (r *Record) Marshal() (data []byte) {
+	data = append(data, RecordVersion)
+	data = append(data, r.GlobalRoot.Bytes()...)
+	data = append(data, r.AnchoredRoot.Bytes()...)
+	data = append(data, types.Uint16Bytes(uint16(len(r.TxRef)))...)
+	data = append(data, r.TxRef...)
+	data = append(data, r.TimeStamp.Bytes()...)
+	data = append(data, r.Receipt.Marshal()...)
+	return data
+}
+
+// Unmarshal
+// Extract a Record from its binary encoding.  Returns an error if the unmarshal fails, or the length of the
+// data consumed and a nil.
+func (r *Record) Unmarshal(data []byte) (dataConsumed int, err error) {
+	defer func() {
+		if rc := recover(); rc != nil {
+			err = errors.New(fmt.Sprintf("Record Failed to unmarshal %v", rc))
+		}
+	}()
+	d := data
+	if data[0] != RecordVersion {
+		return 0, errors.New(fmt.Sprintf("anchor record version %d is not supported", data[0]))
+	}
+	data = data[1:]
+	data = r.GlobalRoot.Extract(data)
+	data = r.AnchoredRoot.Extract(data)
+	var txRefLen uint16
+	txRefLen, data = types.BytesUint16(data)
+	r.TxRef, data = string(data[:txRefLen]), data[txRefLen:]
+	data = r.TimeStamp.Extract(data)
+	consumed, err := r.Receipt.Unmarshal(data)
+	if err != nil {
+		return 0, err
+	}
+	data = data[consumed:]
+	return len(d) - len(data), nil
+}
+
+// GetRecord
+// Get the anchor record for the given global root.  Returns an error if the root has not been anchored.
+func GetRecord(db *database.DB, root types.Hash) (*Record, error) {
+	data := db.Get(types.Anchor, root.Bytes())
+	if data == nil {
+		return nil, errors.New(fmt.Sprintf("root %x has not been anchored", root))
+	}
+	r := new(Record)
+	if _, err := r.Unmarshal(data); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
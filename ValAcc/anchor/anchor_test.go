@@ -0,0 +1,57 @@
+package anchor
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"testing"
+
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/merkleDag"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
+)
+
+// getTestRoots
+// Build some global roots for the tests
+func getTestRoots(cnt int) (roots []types.Hash) {
+	for i := 0; i < cnt; i++ {
+		roots = append(roots, sha256.Sum256([]byte(fmt.Sprint("directory block ", i))))
+	}
+	return roots
+}
+
+func TestRecord(t *testing.T) {
+	roots := getTestRoots(5)
+	md := new(merkleDag.MD)
+	for _, root := range roots {
+		md.AddToChain(root)
+	}
+	r := new(Record)
+	r.GlobalRoot = roots[3]
+	r.AnchoredRoot = *md.GetMDRoot()
+	r.TxRef = "a transaction"
+	r.TimeStamp = types.GetCurrentTimeStamp()
+	r.Receipt.BuildMDReceipt(*md, roots[3])
+	if !r.Validate() {
+		t.Fatal("the record should validate")
+	}
+
+	data := r.Marshal()
+	r2 := new(Record)
+	consumed, err := r2.Unmarshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if consumed != len(data) || !bytes.Equal(r2.Marshal(), data) || r2.TxRef != r.TxRef || !r2.Validate() {
+		t.Error("the record did not survive a round trip")
+	}
+	for cut := 0; cut < len(data); cut++ {
+		if _, err := new(Record).Unmarshal(data[:cut]); err == nil {
+			t.Fatalf("a record truncated to %d bytes should not unmarshal", cut)
+		}
+	}
+
+	r.GlobalRoot = roots[2]
+	if r.Validate() {
+		t.Error("a record whose receipt is for another root should not validate")
+	}
+}
@@ -0,0 +1,104 @@
+package anchor
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
+)
+
+// FileAnchorer
+// A mock Anchorer for testing.  Each hash anchored is appended to a file as a line of hex, and the txRef is
+// the file's path and the line number, i.e. "anchors.txt:12".  Lookup reads the hash back for a txRef.
+type FileAnchorer struct {
+	Path  string     // Path to the file of anchors
+	mutex sync.Mutex // Anchor may be called from many go routines
+	lines int        // Count of lines in the file
+}
+
+// NewFileAnchorer
+// Create a FileAnchorer appending to the file at the given path.  Anchors already in the file are kept.
+func NewFileAnchorer(path string) (*FileAnchorer, error) {
+	f := new(FileAnchorer)
+	f.Path = path
+	hashes, err := f.read()
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	f.lines = len(hashes)
+	return f, nil
+}
+
+// Anchor
+// Append the hash to the file
+func (f *FileAnchorer) Anchor(ctx context.Context, hash types.Hash) (txRef string, err error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	file, err := os.OpenFile(f.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	if _, err := fmt.Fprintf(file, "%x\n", hash); err != nil {
+		return "", err
+	}
+	if err := file.Sync(); err != nil {
+		return "", err
+	}
+	f.lines++
+	return fmt.Sprintf("%s:%d", f.Path, f.lines), nil
+}
+
+// Lookup
+// Return the hash anchored in the given txRef.  Returns an error if there is no such anchor.
+func (f *FileAnchorer) Lookup(txRef string) (types.Hash, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	i := strings.LastIndex(txRef, ":")
+	if i < 0 || txRef[:i] != f.Path {
+		return types.Hash{}, errors.New(fmt.Sprintf("txRef %s is not in %s", txRef, f.Path))
+	}
+	line, err := strconv.Atoi(txRef[i+1:])
+	if err != nil {
+		return types.Hash{}, err
+	}
+	hashes, err := f.read()
+	if err != nil {
+		return types.Hash{}, err
+	}
+	if line < 1 || line > len(hashes) {
+		return types.Hash{}, errors.New(fmt.Sprintf("no anchor at line %d of %s", line, f.Path))
+	}
+	return hashes[line-1], nil
+}
+
+// read
+// Read every hash in the file
+func (f *FileAnchorer) read() (hashes []types.Hash, err error) {
+	file, err := os.Open(f.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		b, err := hex.DecodeString(scanner.Text())
+		if err != nil || len(b) != 32 {
+			return nil, errors.New(fmt.Sprintf("bad anchor on line %d of %s", len(hashes)+1, f.Path))
+		}
+		var h types.Hash
+		h.Extract(b)
+		hashes = append(hashes, h)
+	}
+	return hashes, scanner.Err()
+}
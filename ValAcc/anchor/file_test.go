@@ -0,0 +1,56 @@
+package anchor
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileAnchorer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "anchors.txt")
+	f, err := NewFileAnchorer(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := getTestRoots(3)
+	var txRefs []string
+	for _, root := range roots[:2] {
+		txRef, err := f.Anchor(context.Background(), root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		txRefs = append(txRefs, txRef)
+	}
+
+	// Anchors already in the file are picked up by a new FileAnchorer
+	f2, err := NewFileAnchorer(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txRef, err := f2.Anchor(context.Background(), roots[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	txRefs = append(txRefs, txRef)
+
+	for i, txRef := range txRefs {
+		h, err := f2.Lookup(txRef)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h != roots[i] {
+			t.Errorf("%s should hold root %d", txRef, i)
+		}
+	}
+	for _, bad := range []string{path + ":0", path + ":4", "other.txt:1", path} {
+		if _, err := f2.Lookup(bad); err == nil {
+			t.Errorf("%s should not be found", bad)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := f2.Anchor(ctx, roots[0]); err == nil {
+		t.Error("anchoring with a done context should fail")
+	}
+}
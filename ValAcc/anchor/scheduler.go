@@ -0,0 +1,166 @@
+package anchor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/database"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/merkleDag"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
+)
+
+// Scheduler
+// Collects global roots, and anchors them in batches through an Anchorer.  A batch is anchored
+// once BatchSize roots are waiting, or Interval has passed since the last batch, whichever comes first.
+// Each batch is an MD of its roots, and the MDRoot of the batch is the value anchored.  A Record for every
+// root in the batch is written to the DB.
+//
+// If anchoring a batch fails, the roots are kept and anchored with the next batch.
+type Scheduler struct {
+	DB        *database.DB    // Database holding the anchor records
+	Anchorer  Anchorer        // Where the roots are anchored
+	BatchSize int             // Anchor once this many roots are waiting
+	Interval  time.Duration   // Anchor any roots waiting this often; zero for never
+	roots     chan types.Hash // Roots submitted to be anchored
+	flush     chan chan error // Requests to anchor the roots waiting now
+	done      chan struct{}   // Closed when Run returns
+	pending   []types.Hash    // Roots waiting to be anchored
+}
+
+// NewScheduler
+// Create a Scheduler writing its records to the given database, and anchoring through the given Anchorer.
+func NewScheduler(db *database.DB, anchorer Anchorer, batchSize int, interval time.Duration) *Scheduler {
+	s := new(Scheduler)
+	s.DB = db
+	s.Anchorer = anchorer
+	s.BatchSize = batchSize
+	s.Interval = interval
+	s.roots = make(chan types.Hash, 1000)
+	s.flush = make(chan chan error)
+	s.done = make(chan struct{})
+	return s
+}
+
+// Submit
+// Queue a global root to be anchored.  Returns an error if the Scheduler has stopped, or the
+// context is done before the root is queued.
+func (s *Scheduler) Submit(ctx context.Context, root types.Hash) error {
+	select {
+	case <-s.done: // The roots channel is buffered, so could take a root even though nothing will anchor it
+		return errors.New("the anchor scheduler has stopped")
+	default:
+	}
+	select {
+	case s.roots <- root:
+		return nil
+	case <-s.done:
+		return errors.New("the anchor scheduler has stopped")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Flush
+// Anchor all the roots submitted so far, without waiting for the batch to fill or the Interval to pass.
+func (s *Scheduler) Flush(ctx context.Context) error {
+	request := make(chan error, 1)
+	select {
+	case s.flush <- request:
+	case <-s.done:
+		return errors.New("the anchor scheduler has stopped")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case err := <-request:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Run
+// Collect roots and anchor them until the context is done.  On shutdown, the roots still waiting are
+// anchored (with a fresh context, as ours is done), and any error doing so is returned.
+func (s *Scheduler) Run(ctx context.Context) error {
+	defer close(s.done)
+	var tick <-chan time.Time // With no Interval, only full batches and Flush anchor roots
+	if s.Interval > 0 {
+		ticker := time.NewTicker(s.Interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			s.drain()
+			return s.anchorBatch(context.Background())
+		case root := <-s.roots:
+			s.pending = append(s.pending, root)
+			if len(s.pending) >= s.BatchSize {
+				s.report(s.anchorBatch(ctx))
+			}
+		case request := <-s.flush:
+			s.drain()
+			request <- s.anchorBatch(ctx)
+		case <-tick:
+			s.report(s.anchorBatch(ctx))
+		}
+	}
+}
+
+// drain
+// Move every root already submitted to pending
+func (s *Scheduler) drain() {
+	for len(s.roots) > 0 {
+		s.pending = append(s.pending, <-s.roots)
+	}
+}
+
+// report
+// Anchoring in the background has no one to return an error to, so print it
+func (s *Scheduler) report(err error) {
+	if err != nil {
+		fmt.Printf("failed to anchor %d roots: %v\n", len(s.pending), err)
+	}
+}
+
+// anchorBatch
+// Anchor the MDRoot of the roots pending, and write a Record for each.  The pending roots are only
+// cleared if the anchor and all the records are written.
+func (s *Scheduler) anchorBatch(ctx context.Context) error {
+	if len(s.pending) == 0 {
+		return nil
+	}
+	md := new(merkleDag.MD)
+	for _, root := range s.pending {
+		md.AddToChain(root)
+	}
+	anchoredRoot := *md.GetMDRoot()
+	txRef, err := s.Anchorer.Anchor(ctx, anchoredRoot)
+	if err != nil {
+		return err
+	}
+	timeStamp := types.GetCurrentTimeStamp()
+
+	batch := s.DB.NewBatch()
+	defer batch.Close()
+	for _, root := range s.pending {
+		r := new(Record)
+		r.GlobalRoot = root
+		r.AnchoredRoot = anchoredRoot
+		r.TxRef = txRef
+		r.TimeStamp = timeStamp
+		r.Receipt.BuildMDReceipt(*md, root)
+		if err := batch.Put(types.Anchor, root.Bytes(), r.Marshal()); err != nil {
+			return err
+		}
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	s.pending = s.pending[:0]
+	return nil
+}
@@ -0,0 +1,109 @@
+package anchor
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/database"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
+	dbm "github.com/tendermint/tm-db"
+)
+
+// failingAnchorer
+// An Anchorer that fails until told otherwise
+type failingAnchorer struct {
+	Anchorer
+	fail bool
+}
+
+func (f *failingAnchorer) Anchor(ctx context.Context, hash types.Hash) (string, error) {
+	if f.fail {
+		return "", errors.New("anchoring is down")
+	}
+	return f.Anchorer.Anchor(ctx, hash)
+}
+
+func TestScheduler(t *testing.T) {
+	db := new(database.DB)
+	db.InitDB(dbm.NewMemDB())
+	fileAnchorer, err := NewFileAnchorer(filepath.Join(t.TempDir(), "anchors.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	anchorer := &failingAnchorer{Anchorer: fileAnchorer}
+	s := NewScheduler(db, anchorer, 4, time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+
+	// A full batch is anchored as soon as it fills
+	roots := getTestRoots(11)
+	for _, root := range roots[:4] {
+		if err := s.Submit(ctx, root); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Flush(ctx); err != nil { // Nothing left to anchor, but waits for the batch
+		t.Fatal(err)
+	}
+
+	// A failed batch is kept, and anchored with the next
+	anchorer.fail = true
+	for _, root := range roots[4:6] {
+		s.Submit(ctx, root)
+	}
+	if err := s.Flush(ctx); err == nil {
+		t.Error("the flush should fail while anchoring is down")
+	}
+	if _, err := GetRecord(db, roots[4]); err == nil {
+		t.Error("a root that failed to anchor should have no record")
+	}
+	anchorer.fail = false
+	s.Submit(ctx, roots[6])
+	if err := s.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// Roots waiting at shutdown are anchored
+	for _, root := range roots[7:] {
+		s.Submit(ctx, root)
+	}
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("the scheduler did not shut down")
+	}
+	if err := s.Submit(context.Background(), roots[0]); err == nil {
+		t.Error("submitting to a stopped scheduler should fail")
+	}
+
+	txRefs := make(map[string]int)
+	for i, root := range roots {
+		r, err := GetRecord(db, root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.GlobalRoot != root || !r.Validate() {
+			t.Errorf("the record for root %d should validate", i)
+		}
+		anchored, err := fileAnchorer.Lookup(r.TxRef)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if anchored != r.AnchoredRoot {
+			t.Errorf("the transaction for root %d should hold its anchored root", i)
+		}
+		txRefs[r.TxRef]++
+	}
+	if len(txRefs) != 3 || txRefs[fileAnchorer.Path+":1"] != 4 || txRefs[fileAnchorer.Path+":2"] != 3 {
+		t.Errorf("expected batches of 4, 3 and 4 roots, got %v", txRefs)
+	}
+}
@@ -0,0 +1,155 @@
+package chain
+
+// The chain package reads back the history of a chain from an accumulator's database.  Each chain's nodes
+// form a linked list: NodeFirst holds the hash of the chain's first node, NodeNext links each node to the
+// next, NodeHead holds the hash of the last node, and every node's Previous links back.  Walking forward
+// follows NodeNext, walking backward follows Previous, and both are paginated so a long chain can be
+// replayed a page at a time.
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/database"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/node"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
+)
+
+// DefaultPageSize
+// The count of nodes on a page when no limit is given
+const DefaultPageSize = 100
+
+// Page
+// A run of a chain's nodes, in the order walked.  If the chain has more nodes in that direction, Next is
+// the cursor to pass to get the following page; otherwise Next is nil.
+type Page struct {
+	Nodes  []*node.Node // The nodes on the page
+	Hashes []types.Hash // The hash of each node on the page
+	Next   *types.Hash  // Cursor for the next page, or nil at the end of the chain
+}
+
+// add
+// Add a node to the page
+func (p *Page) add(hash types.Hash, n *node.Node) {
+	p.Hashes = append(p.Hashes, hash)
+	p.Nodes = append(p.Nodes, n)
+}
+
+// getChainNode
+// Get the node with the given hash, and check that it belongs to the chain
+func getChainNode(db *database.DB, chainID types.Hash, nodeHash []byte) (*node.Node, error) {
+	n, err := node.GetNode(db, nodeHash)
+	if err != nil {
+		return nil, err
+	}
+	if n.ChainID != chainID {
+		return nil, errors.New(fmt.Sprintf("node %x belongs to chain %x, not chain %x", nodeHash, n.ChainID, chainID))
+	}
+	return n, nil
+}
+
+// Forward
+// Walk a chain from its first node toward its head, returning up to limit nodes (DefaultPageSize if limit
+// is zero or less).  Pass nil for after to start at the first node, or the Next of the previous page to
+// carry on.  Returns an error if the chain isn't in the database.
+func Forward(db *database.DB, chainID types.Hash, after *types.Hash, limit int) (*Page, error) {
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	var nodeHash []byte
+	if after == nil {
+		if nodeHash = db.Get(types.NodeFirst, chainID[:]); nodeHash == nil {
+			return nil, errors.New(fmt.Sprintf("chain %x not found", chainID))
+		}
+	} else {
+		if _, err := getChainNode(db, chainID, after[:]); err != nil {
+			return nil, err
+		}
+		nodeHash = db.Get(types.NodeNext, after[:])
+	}
+
+	page := new(Page)
+	for ; nodeHash != nil && len(page.Nodes) < limit; nodeHash = db.Get(types.NodeNext, nodeHash) {
+		n, err := getChainNode(db, chainID, nodeHash)
+		if err != nil {
+			return nil, err
+		}
+		var hash types.Hash
+		hash.Extract(nodeHash)
+		page.add(hash, n)
+	}
+	if nodeHash != nil && len(page.Hashes) > 0 {
+		page.Next = page.Hashes[len(page.Hashes)-1].Copy()
+	}
+	return page, nil
+}
+
+// Backward
+// Walk a chain from its head back toward its first node, returning up to limit nodes (DefaultPageSize if
+// limit is zero or less).  Pass nil for before to start at the head, or the Next of the previous page to
+// carry on.  Returns an error if the chain isn't in the database.
+func Backward(db *database.DB, chainID types.Hash, before *types.Hash, limit int) (*Page, error) {
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	var nodeHash []byte
+	if before == nil {
+		if nodeHash = db.Get(types.NodeHead, chainID[:]); nodeHash == nil {
+			return nil, errors.New(fmt.Sprintf("chain %x not found", chainID))
+		}
+	} else {
+		n, err := getChainNode(db, chainID, before[:])
+		if err != nil {
+			return nil, err
+		}
+		if n.SequenceNum > 0 { // Otherwise before is the first node, and there is nothing before it
+			nodeHash = n.Previous.Bytes()
+		}
+	}
+
+	page := new(Page)
+	for nodeHash != nil && len(page.Nodes) < limit {
+		n, err := getChainNode(db, chainID, nodeHash)
+		if err != nil {
+			return nil, err
+		}
+		var hash types.Hash
+		hash.Extract(nodeHash)
+		page.add(hash, n)
+		nodeHash = nil
+		if n.SequenceNum > 0 {
+			nodeHash = n.Previous.Bytes()
+		}
+	}
+	if nodeHash != nil && len(page.Hashes) > 0 {
+		page.Next = page.Hashes[len(page.Hashes)-1].Copy()
+	}
+	return page, nil
+}
+
+// NodeAtHeight
+// Returns the chain's node as of the given block height, i.e. the last node written at or before that
+// height, and its hash.  If the chain was updated at the height, the node is found through the directory
+// block; otherwise the chain is walked back from its head.  Returns an error if the chain isn't in the
+// database, or has no node at or before the height.
+func NodeAtHeight(db *database.DB, chainID types.Hash, height types.BlockHeight) (*node.Node, *types.Hash, error) {
+	if path, err := node.GetNodePath(db, height, chainID); err == nil {
+		return path[0], path[0].GetHash(), nil
+	}
+	var before *types.Hash
+	for {
+		page, err := Backward(db, chainID, before, DefaultPageSize)
+		if err != nil {
+			return nil, nil, err
+		}
+		for i, n := range page.Nodes {
+			if n.BHeight <= height {
+				return n, page.Hashes[i].Copy(), nil
+			}
+		}
+		if page.Next == nil {
+			return nil, nil, errors.New(fmt.Sprintf("chain %x has no node at or before height %d", chainID, height))
+		}
+		before = page.Next
+	}
+}
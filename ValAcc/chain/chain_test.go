@@ -0,0 +1,162 @@
+package chain
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/accumulator"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/database"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/node"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
+	dbm "github.com/tendermint/tm-db"
+)
+
+// testHeights
+// The block heights at which the test chain is written.  Block 2 only holds another chain.
+var testHeights = []types.BlockHeight{0, 1, 3, 4, 5}
+
+// getTestEntry
+// Build an EntryHash for the given chain and entry number
+func getTestEntry(chainID types.Hash, entry int) (eh node.EntryHash) {
+	eh.ChainID = chainID
+	eh.EntryHash = sha256.Sum256(append(chainID[:], fmt.Sprint(" entry ", entry)...))
+	return eh
+}
+
+// buildTestChain
+// Run an accumulator over an in memory database for six blocks, writing two entries to the test chain in
+// each of testHeights, and one entry to another chain in every block.  Returns the database and the ChainID
+// of the test chain.
+func buildTestChain(t *testing.T) (*database.DB, types.Hash) {
+	db := new(database.DB)
+	db.InitDB(dbm.NewMemDB())
+	accID := types.Hash(sha256.Sum256([]byte("TestAcc DID")))
+	acc := new(accumulator.Accumulator)
+	acc.Init(db, &accID)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	go acc.Run(ctx)
+
+	chainID := types.Hash(sha256.Sum256([]byte("test chain")))
+	other := types.Hash(sha256.Sum256([]byte("other chain")))
+	next := 0
+	for height := types.BlockHeight(0); height < 6; height++ {
+		for _, h := range testHeights {
+			if h != height {
+				continue
+			}
+			for i := 0; i < 2; i++ {
+				acc.GetEntryFeed() <- getTestEntry(chainID, next)
+				next++
+			}
+		}
+		acc.GetEntryFeed() <- getTestEntry(other, int(height))
+		if result, err := acc.EndBlock(ctx); err != nil || result.Err != nil {
+			t.Fatal(err, result)
+		}
+	}
+	return db, chainID
+}
+
+func TestForwardBackward(t *testing.T) {
+	db, chainID := buildTestChain(t)
+
+	var after *types.Hash
+	var heights []types.BlockHeight
+	for pages := 0; ; pages++ {
+		page, err := Forward(db, chainID, after, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, n := range page.Nodes {
+			if int(n.SequenceNum) != len(heights) {
+				t.Errorf("expected sequence number %d, got %d", len(heights), n.SequenceNum)
+			}
+			if *n.GetHash() != page.Hashes[i] {
+				t.Error("the hashes on the page should be the hashes of its nodes")
+			}
+			heights = append(heights, n.BHeight)
+		}
+		if page.Next == nil {
+			if pages != 2 {
+				t.Errorf("expected 3 pages of up to 2 nodes, got %d", pages+1)
+			}
+			break
+		}
+		after = page.Next
+	}
+	if len(heights) != len(testHeights) {
+		t.Fatalf("expected %d nodes walking forward, got %d", len(testHeights), len(heights))
+	}
+	for i := range heights {
+		if heights[i] != testHeights[i] {
+			t.Errorf("expected node %d at height %d, got %d", i, testHeights[i], heights[i])
+		}
+	}
+
+	var before *types.Hash
+	heights = heights[:0]
+	for {
+		page, err := Backward(db, chainID, before, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, n := range page.Nodes {
+			heights = append(heights, n.BHeight)
+		}
+		if page.Next == nil {
+			break
+		}
+		before = page.Next
+	}
+	if len(heights) != len(testHeights) {
+		t.Fatalf("expected %d nodes walking backward, got %d", len(testHeights), len(heights))
+	}
+	for i := range heights {
+		if heights[i] != testHeights[len(testHeights)-1-i] {
+			t.Errorf("expected node %d from the head at height %d, got %d", i, testHeights[len(testHeights)-1-i], heights[i])
+		}
+	}
+
+	// A full page that ends the chain has no Next
+	if page, err := Forward(db, chainID, nil, len(testHeights)); err != nil || page.Next != nil {
+		t.Error("a page holding the whole chain should have no Next")
+	}
+
+	if _, err := Forward(db, types.Hash{1}, nil, 0); err == nil {
+		t.Error("walking a chain that isn't in the database should fail")
+	}
+	if _, err := Backward(db, types.Hash{1}, nil, 0); err == nil {
+		t.Error("walking a chain that isn't in the database should fail")
+	}
+	other := types.Hash(sha256.Sum256([]byte("other chain")))
+	if _, err := Forward(db, other, after, 0); err == nil {
+		t.Error("a cursor from another chain should be rejected")
+	}
+}
+
+func TestNodeAtHeight(t *testing.T) {
+	db, chainID := buildTestChain(t)
+
+	expected := map[types.BlockHeight]types.BlockHeight{0: 0, 1: 1, 2: 1, 3: 3, 4: 4, 5: 5, 9: 5}
+	for height, want := range expected {
+		n, hash, err := NodeAtHeight(db, chainID, height)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n.BHeight != want {
+			t.Errorf("expected the node at height %d as of height %d, got height %d", want, height, n.BHeight)
+		}
+		if *n.GetHash() != *hash {
+			t.Error("the hash returned should be the hash of the node")
+		}
+	}
+
+	if _, _, err := NodeAtHeight(db, types.Hash{1}, 0); err == nil {
+		t.Error("a chain that isn't in the database has no node at any height")
+	}
+}
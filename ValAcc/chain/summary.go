@@ -0,0 +1,50 @@
+package chain
+
+import (
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/accumulator"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/database"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
+)
+
+// Summary
+// The extent of a chain, as of its head
+type Summary struct {
+	ChainID     types.Hash        // The chain
+	FirstHeight types.BlockHeight // Block height of the chain's first node
+	LastHeight  types.BlockHeight // Block height of the chain's head
+	NodeCount   uint64            // Count of the chain's nodes
+	EntryCount  uint64            // Count of the entries recorded in the chain over all its nodes
+	Head        types.Hash        // Hash of the chain's head
+	MDRoot      types.Hash        // ListMDRoot of the head, the MD root over every entry in the chain
+}
+
+// GetSummary
+// Summarize a chain without walking it.  The node count comes from the sequence number of the head, and
+// the entry count from the MD state stored with the head.  Returns an error if the chain isn't in the
+// database.
+func GetSummary(db *database.DB, chainID types.Hash) (*Summary, error) {
+	back, err := Backward(db, chainID, nil, 1)
+	if err != nil {
+		return nil, err
+	}
+	fwd, err := Forward(db, chainID, nil, 1)
+	if err != nil {
+		return nil, err
+	}
+	head, first := back.Nodes[0], fwd.Nodes[0]
+
+	md, err := accumulator.GetChainMD(db, back.Hashes[0], head)
+	if err != nil {
+		return nil, err
+	}
+
+	s := new(Summary)
+	s.ChainID = chainID
+	s.FirstHeight = first.BHeight
+	s.LastHeight = head.BHeight
+	s.NodeCount = uint64(head.SequenceNum) + 1
+	s.EntryCount = md.Count()
+	s.Head = back.Hashes[0]
+	s.MDRoot = head.ListMDRoot
+	return s, nil
+}
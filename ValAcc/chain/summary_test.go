@@ -0,0 +1,31 @@
+package chain
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
+)
+
+func TestGetSummary(t *testing.T) {
+	db, chainID := buildTestChain(t)
+
+	s, err := GetSummary(db, chainID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.ChainID != chainID || s.FirstHeight != 0 || s.LastHeight != 5 {
+		t.Errorf("expected the chain to run from height 0 to 5, got %d to %d", s.FirstHeight, s.LastHeight)
+	}
+	if s.NodeCount != uint64(len(testHeights)) || s.EntryCount != uint64(2*len(testHeights)) {
+		t.Errorf("expected %d nodes and %d entries, got %d nodes and %d entries",
+			len(testHeights), 2*len(testHeights), s.NodeCount, s.EntryCount)
+	}
+	if !bytes.Equal(db.Get(types.NodeHead, chainID[:]), s.Head[:]) {
+		t.Error("the summary should name the head of the chain")
+	}
+
+	if _, err := GetSummary(db, types.Hash{1}); err == nil {
+		t.Error("summarizing a chain that isn't in the database should fail")
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+// accumulator
+// Run one accumulator as its own process, serving a router over TCP (see the remote package).  Start one
+// of these for each accumulator, then run the router with the addresses of all of them, in order of index.
+//
+// Usage:     accumulator -i 0 -listen 127.0.0.1:7000
+//            accumulator -i 1 -listen 127.0.0.1:7001
+//            ValAcc -remote 127.0.0.1:7000,127.0.0.1:7001
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/remote"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/router"
+)
+
+func main() {
+	config := router.DefaultConfig()
+	IndexPtr := flag.Int("i", 0, "the index of this accumulator under the router")
+	ListenPtr := flag.String("listen", "127.0.0.1:7000", "the address to serve the router on")
+	WindowPtr := flag.Int("window", remote.DefaultWindow, "the entries the router may send ahead of the accumulator")
+	flag.StringVar(&config.DataDir, "datadir", config.DataDir, "the directory holding the databases")
+	flag.StringVar(&config.Backend, "backend", config.Backend, "the database backend: goleveldb, cleveldb, badgerdb, boltdb, rocksdb or memdb")
+	flag.Parse()
+
+	// The same database and Digital ID as the router gives an accumulator with this index in its own process
+	acc, err := config.OpenAccumulator(*IndexPtr)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	listener, err := net.Listen("tcp", *ListenPtr)
+	if err != nil {
+		fmt.Printf("failed to listen on %s: %v\n", *ListenPtr, err)
+		acc.Close()
+		os.Exit(1)
+	}
+	fmt.Printf("Accumulator %d serving on %s\n", *IndexPtr, listener.Addr())
+
+	ctx, stop := context.WithCancel(context.Background())
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		<-interrupt
+		stop() // Seal the block in flight and close the database
+	}()
+	if err := remote.NewServer(acc, *WindowPtr).Serve(ctx, listener); err != nil {
+		fmt.Printf("Accumulator %d failed to shut down cleanly: %v\n", *IndexPtr, err)
+		os.Exit(1)
+	}
+}
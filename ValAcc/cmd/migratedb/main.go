@@ -0,0 +1,58 @@
+package main
+
+// migratedb
+// Rewrite databases written with version 0 keys (the bucket simply joined to the key) into the current key
+// layout (see database.KeyVersion), checking every record afterwards.  Databases of the current version are
+// left alone, so it is safe to run more than once, and to run again after being interrupted.
+//
+// Usage:     migratedb [-datadir <directory>] [-backend goleveldb]   # The router's and every accumulator's
+//            migratedb -dir accumulator_0.db -name accumulator_0.db   # Just the one database
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/database"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/router"
+)
+
+// migrate
+// Migrate one database, and report how it went.  Returns false on a failure.
+func migrate(backend, dir, name string) bool {
+	migrated, err := database.Migrate(backend, dir, name)
+	if err != nil {
+		fmt.Printf("%s: failed after migrating %d records: %v\n", dir, migrated, err)
+		return false
+	}
+	fmt.Printf("%s: migrated %d records\n", dir, migrated)
+	return true
+}
+
+func main() {
+	config := router.DefaultConfig()
+	flag.StringVar(&config.DataDir, "datadir", config.DataDir, "the directory holding the router's and accumulators' databases")
+	flag.StringVar(&config.Backend, "backend", config.Backend, "the database backend: goleveldb, cleveldb, badgerdb, boltdb or rocksdb")
+	DirPtr := flag.String("dir", "", "migrate only the database in this directory")
+	NamePtr := flag.String("name", "", "the name of the database in -dir")
+	flag.Parse()
+
+	if *DirPtr != "" {
+		if *NamePtr == "" || !migrate(config.Backend, *DirPtr, *NamePtr) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	ok := migrate(config.Backend, filepath.Join(config.DataDir, config.RouterPath), "router")
+	for i := 0; ; i++ {
+		if _, err := os.Stat(config.AccumulatorDir(i)); os.IsNotExist(err) {
+			break // No more accumulators
+		}
+		ok = migrate(config.Backend, config.AccumulatorDir(i), "accumulator") && ok
+	}
+	if !ok {
+		os.Exit(1)
+	}
+}
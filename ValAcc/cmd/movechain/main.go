@@ -0,0 +1,75 @@
+package main
+
+// movechain
+// Move a chain from the database of one accumulator to another, while the accumulators are stopped.  The
+// router moves chains itself when the count of accumulators or the shard strategy changes; this tool is for
+// moving a single chain by hand, say to finish a move that was interrupted, or to follow a new pin in a
+// Pinned strategy.
+//
+// Usage:     movechain -from 0 -to 1 -chain <ChainID in hex>
+
+import (
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/accumulator"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/router"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
+)
+
+func main() {
+	config := router.DefaultConfig()
+	FromPtr := flag.Int("from", -1, "the index of the accumulator holding the chain")
+	ToPtr := flag.Int("to", -1, "the index of the accumulator to move the chain to")
+	ChainPtr := flag.String("chain", "", "the ChainID of the chain to move, in hex")
+	flag.StringVar(&config.DataDir, "datadir", config.DataDir, "the directory holding the databases")
+	flag.StringVar(&config.Backend, "backend", config.Backend, "the database backend: goleveldb, cleveldb, badgerdb, boltdb or rocksdb")
+	flag.Parse()
+
+	chainBytes, err := hex.DecodeString(*ChainPtr)
+	if *FromPtr < 0 || *ToPtr < 0 || *FromPtr == *ToPtr || err != nil || len(chainBytes) != len(types.Hash{}) {
+		fmt.Println("Usage:     movechain -from 0 -to 1 -chain <ChainID in hex>")
+		os.Exit(1)
+	}
+	var chainID types.Hash
+	chainID.Extract(chainBytes)
+
+	if err := moveChain(config, *FromPtr, *ToPtr, chainID); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	fmt.Printf("Moved chain %x from accumulator %d to accumulator %d\n", chainID, *FromPtr, *ToPtr)
+}
+
+// moveChain
+// Open both accumulators, replaying their write-ahead logs, and move the chain.  A chain can only be moved at
+// a block boundary, so if either write-ahead log holds a block in flight, nothing is moved.  That block must
+// be sealed by the router (start it, and stop it again), so the block gets a global root.  Otherwise the
+// entries replayed on the next start would recreate the chain on the source, or be added to the chain on the
+// destination as if it were a new chain.  The accumulators are closed before returning.
+func moveChain(config *router.Config, from, to int, chainID types.Hash) error {
+	src, err := config.OpenAccumulator(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := config.OpenAccumulator(to)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	for i, acc := range map[int]*accumulator.Accumulator{from: src, to: dst} {
+		if acc.Pending() {
+			return errors.New(fmt.Sprintf("accumulator %d has a block in flight in its write-ahead log; "+
+				"start and stop the router to seal it before moving chains", i))
+		}
+	}
+	if err := accumulator.MigrateChain(src.DB, dst.DB, chainID); err != nil {
+		return errors.New(fmt.Sprintf("failed to move chain %x: %v", chainID, err))
+	}
+	return nil
+}
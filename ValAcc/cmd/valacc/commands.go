@@ -0,0 +1,353 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/accumulator"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/chain"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/database"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/merkleDag"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/node"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
+)
+
+// hx
+// A hash in hex, as hashes are shown in all output
+func hx(h types.Hash) string {
+	return hex.EncodeToString(h[:])
+}
+
+// timeOf
+// The time of a node's TimeStamp, which is in nanoseconds
+func timeOf(ts types.TimeStamp) string {
+	return time.Unix(0, int64(ts)).UTC().Format(time.RFC3339Nano)
+}
+
+// nodeOutput
+// A chain node or directory block, as output
+type nodeOutput struct {
+	Hash       string   `json:"hash"`
+	ChainID    string   `json:"chainID"`
+	Height     uint32   `json:"height"`
+	Sequence   uint32   `json:"sequence"`
+	TimeStamp  string   `json:"timeStamp"`
+	Previous   string   `json:"previous"`
+	ListMDRoot string   `json:"listMDRoot"`
+	MDRoot     string   `json:"mdRoot"`
+	EntryCount int      `json:"entryCount"`
+	Entries    []string `json:"entries,omitempty"`
+}
+
+// newNodeOutput
+// Convert a node for output, listing its entries if asked
+func newNodeOutput(hash types.Hash, n *node.Node, entries bool) *nodeOutput {
+	out := new(nodeOutput)
+	out.Hash = hx(hash)
+	out.ChainID = hx(n.ChainID)
+	out.Height = uint32(n.BHeight)
+	out.Sequence = uint32(n.SequenceNum)
+	out.TimeStamp = timeOf(n.TimeStamp)
+	out.Previous = hx(n.Previous)
+	out.ListMDRoot = hx(n.ListMDRoot)
+	out.MDRoot = hx(*n.GetMDRoot())
+	out.EntryCount = len(n.EntryList)
+	if entries {
+		for _, e := range n.EntryList {
+			out.Entries = append(out.Entries, hx(e))
+		}
+	}
+	return out
+}
+
+// print
+// Write the node in readable form
+func (n *nodeOutput) print() {
+	fmt.Printf("  Hash        %s\n", n.Hash)
+	fmt.Printf("  ChainID     %s\n", n.ChainID)
+	fmt.Printf("  Height      %d\n", n.Height)
+	fmt.Printf("  Sequence    %d\n", n.Sequence)
+	fmt.Printf("  TimeStamp   %s\n", n.TimeStamp)
+	fmt.Printf("  Previous    %s\n", n.Previous)
+	fmt.Printf("  ListMDRoot  %s\n", n.ListMDRoot)
+	fmt.Printf("  MDRoot      %s\n", n.MDRoot)
+	fmt.Printf("  Entries     %d\n", n.EntryCount)
+	for _, e := range n.Entries {
+		fmt.Printf("    %s\n", e)
+	}
+}
+
+// chainListing
+// A chain listed in a directory block, and the MDRoot of its node in the block
+type chainListing struct {
+	ChainID string `json:"chainID"`
+	MDRoot  string `json:"mdRoot"`
+}
+
+// dblockOutput
+// A directory block, and every chain updated in its block
+type dblockOutput struct {
+	*nodeOutput
+	Chains []chainListing `json:"chains"`
+}
+
+// dblockCmd
+// Dump the directory block at a height
+func dblockCmd(o *options, db *database.DB, args []string) error {
+	arg, err := oneArg("dblock", args)
+	if err != nil {
+		return err
+	}
+	height, err := parseHeight(arg)
+	if err != nil {
+		return err
+	}
+	directoryBlock, err := node.GetDirectoryBlock(db, height)
+	if err != nil {
+		return err
+	}
+	list, err := node.GetChainsAtHeight(db, height)
+	if err != nil {
+		return err
+	}
+
+	out := dblockOutput{nodeOutput: newNodeOutput(*directoryBlock.GetHash(), directoryBlock, false)}
+	out.Chains = []chainListing{}
+	for _, ne := range list {
+		out.Chains = append(out.Chains, chainListing{ChainID: hx(ne.ChainID), MDRoot: hx(ne.MDRoot)})
+	}
+	return o.output(out, func() {
+		fmt.Printf("Directory block %d\n", height)
+		out.print()
+		fmt.Printf("  Chains      %d\n", len(out.Chains))
+		for _, c := range out.Chains {
+			fmt.Printf("    %s  %s\n", c.ChainID, c.MDRoot)
+		}
+	})
+}
+
+// chainOutput
+// The summary of a chain, and a page of its nodes
+type chainOutput struct {
+	ChainID     string        `json:"chainID"`
+	FirstHeight uint32        `json:"firstHeight"`
+	LastHeight  uint32        `json:"lastHeight"`
+	NodeCount   uint64        `json:"nodeCount"`
+	EntryCount  uint64        `json:"entryCount"`
+	Head        string        `json:"head"`
+	MDRoot      string        `json:"mdRoot"`
+	Nodes       []*nodeOutput `json:"nodes"`
+	Next        string        `json:"next,omitempty"` // Pass as -after to get the next page
+}
+
+// chainCmd
+// Summarize a chain, and walk a page of its nodes from the first (or from the head with -reverse)
+func chainCmd(o *options, db *database.DB, args []string) error {
+	flags := flag.NewFlagSet("chain", flag.ContinueOnError)
+	after := flags.String("after", "", "start the page after this node (the next of the previous page)")
+	limit := flags.Int("limit", chain.DefaultPageSize, "the most nodes on the page")
+	reverse := flags.Bool("reverse", false, "walk back from the head of the chain")
+	entries := flags.Bool("entries", false, "list the entries of each node")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	arg, err := oneArg("chain", flags.Args())
+	if err != nil {
+		return err
+	}
+	chainID, err := parseHash(arg)
+	if err != nil {
+		return err
+	}
+	var cursor *types.Hash
+	if *after != "" {
+		h, err := parseHash(*after)
+		if err != nil {
+			return err
+		}
+		cursor = &h
+	}
+
+	summary, err := chain.GetSummary(db, chainID)
+	if err != nil {
+		return err
+	}
+	var page *chain.Page
+	if *reverse {
+		page, err = chain.Backward(db, chainID, cursor, *limit)
+	} else {
+		page, err = chain.Forward(db, chainID, cursor, *limit)
+	}
+	if err != nil {
+		return err
+	}
+
+	out := new(chainOutput)
+	out.ChainID = hx(summary.ChainID)
+	out.FirstHeight = uint32(summary.FirstHeight)
+	out.LastHeight = uint32(summary.LastHeight)
+	out.NodeCount = summary.NodeCount
+	out.EntryCount = summary.EntryCount
+	out.Head = hx(summary.Head)
+	out.MDRoot = hx(summary.MDRoot)
+	out.Nodes = []*nodeOutput{}
+	for i, n := range page.Nodes {
+		out.Nodes = append(out.Nodes, newNodeOutput(page.Hashes[i], n, *entries))
+	}
+	if page.Next != nil {
+		out.Next = hx(*page.Next)
+	}
+	return o.output(out, func() {
+		fmt.Printf("Chain %s\n", out.ChainID)
+		fmt.Printf("  Heights     %d to %d\n", out.FirstHeight, out.LastHeight)
+		fmt.Printf("  Nodes       %d\n", out.NodeCount)
+		fmt.Printf("  Entries     %d\n", out.EntryCount)
+		fmt.Printf("  Head        %s\n", out.Head)
+		fmt.Printf("  MDRoot      %s\n", out.MDRoot)
+		for _, n := range out.Nodes {
+			fmt.Printf("\nNode %d at height %d\n", n.Sequence, n.Height)
+			n.print()
+		}
+		if out.Next != "" {
+			fmt.Printf("\nMore nodes follow; pass -after %s for the next page\n", out.Next)
+		}
+	})
+}
+
+// entryOutput
+// Where an entry is recorded
+type entryOutput struct {
+	EntryHash string `json:"entryHash"`
+	ChainID   string `json:"chainID"`
+	Height    uint32 `json:"height"`
+	NodeHash  string `json:"nodeHash"`
+	Sequence  uint32 `json:"sequence"`
+	Position  int    `json:"position"`
+}
+
+// entryCmd
+// Show the chain node, and the position in it, where an entry is recorded
+func entryCmd(o *options, db *database.DB, args []string) error {
+	arg, err := oneArg("entry", args)
+	if err != nil {
+		return err
+	}
+	entryHash, err := parseHash(arg)
+	if err != nil {
+		return err
+	}
+	loc, err := node.GetEntryLocation(db, entryHash)
+	if err != nil {
+		return err
+	}
+
+	out := entryOutput{
+		EntryHash: hx(entryHash),
+		ChainID:   hx(loc.Node.ChainID),
+		Height:    uint32(loc.BHeight),
+		NodeHash:  hx(loc.NodeHash),
+		Sequence:  uint32(loc.Node.SequenceNum),
+		Position:  loc.Position,
+	}
+	return o.output(out, func() {
+		fmt.Printf("Entry %s\n", out.EntryHash)
+		fmt.Printf("  ChainID     %s\n", out.ChainID)
+		fmt.Printf("  Height      %d\n", out.Height)
+		fmt.Printf("  Node        %s (sequence %d)\n", out.NodeHash, out.Sequence)
+		fmt.Printf("  Position    %d\n", out.Position)
+	})
+}
+
+// receiptCmd
+// Emit the receipt proving an entry against the ListMDRoot of its chain node, in hex of the binary
+// encoding, or in the canonical JSON encoding with -json.  Either can be checked with verify-receipt.
+func receiptCmd(o *options, db *database.DB, args []string) error {
+	arg, err := oneArg("receipt", args)
+	if err != nil {
+		return err
+	}
+	entryHash, err := parseHash(arg)
+	if err != nil {
+		return err
+	}
+	receipt, _, err := accumulator.BuildReceiptFromDB(db, entryHash)
+	if err != nil {
+		return err
+	}
+	return o.output(receipt, func() {
+		fmt.Println(hex.EncodeToString(receipt.Marshal()))
+	})
+}
+
+// verifyOutput
+// The result of checking a receipt
+type verifyOutput struct {
+	Valid     bool   `json:"valid"`
+	EntryHash string `json:"entryHash"`
+	MDRoot    string `json:"mdRoot"`
+}
+
+// readReceipt
+// Decode a receipt in its JSON encoding, its binary encoding in hex, or its binary encoding
+func readReceipt(data []byte) (*merkleDag.MDReceipt, error) {
+	receipt := new(merkleDag.MDReceipt)
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		if err := json.Unmarshal(trimmed, receipt); err != nil {
+			return nil, err
+		}
+		return receipt, nil
+	}
+	if binary, err := hex.DecodeString(string(trimmed)); err == nil {
+		data = binary
+	}
+	n, err := receipt.Unmarshal(data)
+	if err != nil {
+		return nil, err
+	}
+	if n != len(data) {
+		return nil, errors.New(fmt.Sprintf("%d bytes follow the receipt", len(data)-n))
+	}
+	return receipt, nil
+}
+
+// verifyReceiptCmd
+// Check a receipt read from a file, without the database.  A valid receipt proves its entry hash against
+// its MDRoot, which should then be checked against the ListMDRoot of the chain node the entry is in.
+func verifyReceiptCmd(o *options, _ *database.DB, args []string) error {
+	arg, err := oneArg("verify-receipt", args)
+	if err != nil {
+		return err
+	}
+	data, err := ioutil.ReadFile(arg)
+	if err != nil {
+		return err
+	}
+	receipt, err := readReceipt(data)
+	if err != nil {
+		return errors.New(fmt.Sprintf("failed to read the receipt in %s: %v", arg, err))
+	}
+
+	out := verifyOutput{Valid: receipt.Validate(), EntryHash: hx(receipt.EntryHash), MDRoot: hx(receipt.MDRoot)}
+	if err := o.output(out, func() {
+		result := "valid"
+		if !out.Valid {
+			result = "INVALID"
+		}
+		fmt.Printf("Receipt is %s\n", result)
+		fmt.Printf("  EntryHash   %s\n", out.EntryHash)
+		fmt.Printf("  MDRoot      %s\n", out.MDRoot)
+	}); err != nil {
+		return err
+	}
+	if !out.Valid {
+		return errors.New("the receipt does not prove the entry hash against the MDRoot")
+	}
+	return nil
+}
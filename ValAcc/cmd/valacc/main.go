@@ -0,0 +1,164 @@
+package main
+
+// valacc
+// Query and inspect the database of an accumulator.  The database is opened read only, so it can be read
+// while the accumulator is stopped, or from a copy.  Output is human readable, or JSON with -json.
+//
+// Usage:     valacc [-datadir <directory>] [-acc 0 | -db <path>/accumulator.db] [-json] <command> <args>
+//
+//	dblock <height>                                   Dump the directory block at a height
+//	chain [-after <node>] [-limit n] [-reverse] [-entries] <id>
+//	                                                  Summarize a chain, and walk a page of its nodes
+//	entry <hash>                                      Show where an entry is recorded
+//	receipt <entry hash>                              Emit a receipt for an entry (hex, or JSON with -json)
+//	verify-receipt <file>                             Check a receipt (hex, binary or JSON) offline
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/database"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/router"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
+)
+
+const usage = `Usage:     valacc [-datadir <directory>] [-acc 0 | -db <path>/accumulator.db] [-json] <command> <args>
+
+Commands:
+  dblock <height>                                   Dump the directory block at a height
+  chain [-after <node>] [-limit n] [-reverse] [-entries] <id>
+                                                    Summarize a chain, and walk a page of its nodes
+  entry <hash>                                      Show where an entry is recorded
+  receipt <entry hash>                              Emit a receipt for an entry (hex, or JSON with -json)
+  verify-receipt <file>                             Check a receipt (hex, binary or JSON) offline
+
+Flags:
+`
+
+// options
+// The global flags, shared by every command
+type options struct {
+	config *router.Config // Where the accumulators' databases are
+	acc    int            // Index of the accumulator whose database is read
+	dbPath string         // Path of the database, overriding the config and acc
+	json   bool           // Write JSON rather than human readable output
+}
+
+// command
+// A subcommand, and whether it reads the database
+type command struct {
+	needDB bool
+	run    func(o *options, db *database.DB, args []string) error
+}
+
+var commands = map[string]command{
+	"dblock":         {true, dblockCmd},
+	"chain":          {true, chainCmd},
+	"entry":          {true, entryCmd},
+	"receipt":        {true, receiptCmd},
+	"verify-receipt": {false, verifyReceiptCmd},
+}
+
+func main() {
+	o := new(options)
+	o.config = router.DefaultConfig()
+	flag.StringVar(&o.config.DataDir, "datadir", o.config.DataDir, "the directory holding the databases")
+	flag.IntVar(&o.acc, "acc", 0, "the index of the accumulator whose database is read")
+	flag.StringVar(&o.dbPath, "db", "", "the path of the database to read (a goleveldb .db directory), instead of -datadir and -acc")
+	flag.BoolVar(&o.json, "json", false, "write JSON rather than human readable output")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	cmd, ok := commands[flag.Arg(0)]
+	if !ok {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var db *database.DB
+	if cmd.needDB {
+		var err error
+		if db, err = o.openDB(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	err := cmd.run(o, db, flag.Args()[1:])
+	if db != nil {
+		db.Close()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// openDB
+// Open the database named by -db, or else the database of accumulator -acc under -datadir, read only
+func (o *options) openDB() (*database.DB, error) {
+	dir, name := o.config.AccumulatorDir(o.acc), "accumulator"
+	if o.dbPath != "" {
+		dir, name = filepath.Split(filepath.Clean(o.dbPath))
+		name = strings.TrimSuffix(name, ".db")
+	}
+	db, err := database.OpenReadOnly(o.config.Backend, dir, name)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("failed to open the database: %v", err))
+	}
+	return db, nil
+}
+
+// output
+// Write the value as indented JSON with -json, or else call human to write it in readable form
+func (o *options) output(v interface{}, human func()) error {
+	if !o.json {
+		human()
+		return nil
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+// oneArg
+// Check that a command was given exactly one argument, and return it
+func oneArg(name string, args []string) (string, error) {
+	if len(args) != 1 {
+		return "", errors.New(fmt.Sprintf("%s takes one argument (see valacc -h)", name))
+	}
+	return args[0], nil
+}
+
+// parseHash
+// Parse a hash given in hex
+func parseHash(s string) (hash types.Hash, err error) {
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != len(hash) {
+		return hash, errors.New(fmt.Sprintf("%q is not a %d byte hash in hex", s, len(hash)))
+	}
+	hash.Extract(b)
+	return hash, nil
+}
+
+// parseHeight
+// Parse a block height
+func parseHeight(s string) (types.BlockHeight, error) {
+	height, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return 0, errors.New(fmt.Sprintf("%q is not a block height", s))
+	}
+	return types.BlockHeight(height), nil
+}
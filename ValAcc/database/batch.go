@@ -0,0 +1,106 @@
+package database
+
+import (
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
+	dbm "github.com/tendermint/tm-db"
+)
+
+// Store
+// The reads and writes needed to record nodes.  Both DB and Batch are Stores, so nodes can be written
+// straight to the database, or collected in a Batch and committed together.
+type Store interface {
+	Get(bucket string, key []byte) (value []byte)
+	GetInt32(bucket string, ikey uint32) (value []byte)
+	Put(bucket string, key []byte, value []byte) error
+	PutInt32(bucket string, ikey int, value []byte) error
+	Delete(bucket string, key []byte) error
+	Has(bucket string, key []byte) (bool, error)
+}
+
+// Batch
+// Collects a set of writes to the database that are committed atomically by Write.  Reads through the
+// Batch see the values written to the Batch before they are committed.  A Batch is not safe for use
+// by multiple go routines.
+type Batch struct {
+	db      *DB               // Database the batch is committed to
+	batch   dbm.Batch         // Underlying batch of the database
+	pending map[string][]byte // Values written to the batch, by combined key
+}
+
+// NewBatch
+// Start a batch of writes to the database.  The caller must Close the batch when done.
+func (d *DB) NewBatch() *Batch {
+	b := new(Batch)
+	b.db = d
+	b.batch = d.db2.NewBatch()
+	b.pending = make(map[string][]byte)
+	return b
+}
+
+// Get
+// Look in the given bucket, and return the key found, either in the batch or in the database.
+// Returns nil if no value is found for the given key
+func (b *Batch) Get(bucket string, key []byte) (value []byte) {
+	if value, ok := b.pending[string(GetKey(bucket, key))]; ok {
+		return value
+	}
+	return b.db.Get(bucket, key)
+}
+
+// Has
+// Returns true if the key is in the bucket, either written to the batch or in the database, and not
+// deleted by the batch
+func (b *Batch) Has(bucket string, key []byte) (bool, error) {
+	if value, ok := b.pending[string(GetKey(bucket, key))]; ok {
+		return value != nil, nil
+	}
+	return b.db.Has(bucket, key)
+}
+
+func (b *Batch) GetInt32(bucket string, ikey uint32) (value []byte) {
+	key := types.Uint32Bytes(ikey)
+	return b.Get(bucket, key)
+}
+
+// Put
+// Add a key/value to the batch.  Nothing is written to the database until the batch is committed.
+func (b *Batch) Put(bucket string, key []byte, value []byte) error {
+	CKey := GetKey(bucket, key)
+	if err := b.batch.Set(CKey, value); err != nil {
+		return err
+	}
+	b.pending[string(CKey)] = value
+	return nil
+}
+
+// PutInt32
+// Add a key/value to the batch, where the key is an index.
+func (b *Batch) PutInt32(bucket string, ikey int, value []byte) error {
+	key := types.Uint32Bytes(uint32(ikey))
+	return b.Put(bucket, key, value)
+}
+
+// Delete
+// Add the removal of a key to the batch.  Reads through the batch no longer see the key.
+func (b *Batch) Delete(bucket string, key []byte) error {
+	CKey := GetKey(bucket, key)
+	if err := b.batch.Delete(CKey); err != nil {
+		return err
+	}
+	b.pending[string(CKey)] = nil // A nil in pending is a deleted key
+	return nil
+}
+
+// Write
+// Commit all the writes in the batch to the database in one atomic write, flushed to storage
+// before returning.  Only Close may be called after Write.
+func (b *Batch) Write() error {
+	return b.batch.WriteSync()
+}
+
+// Close
+// Release the batch.  Any writes not committed by Write are discarded.
+func (b *Batch) Close() error {
+	b.pending = nil
+	return b.batch.Close()
+}
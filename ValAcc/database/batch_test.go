@@ -0,0 +1,48 @@
+package database
+
+import (
+	"bytes"
+	"testing"
+
+	dbm "github.com/tendermint/tm-db"
+)
+
+func TestBatch(t *testing.T) {
+	db := new(DB)
+	db.InitDB(dbm.NewMemDB())
+
+	batch := db.NewBatch()
+	if err := batch.Put("test", []byte("answer"), []byte("42")); err != nil {
+		t.Fatal(err)
+	}
+	if err := batch.PutInt32("test", 7, []byte("seven")); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(batch.Get("test", []byte("answer")), []byte("42")) {
+		t.Error("reads through the batch should see the writes to the batch")
+	}
+	if db.Get("test", []byte("answer")) != nil {
+		t.Error("writes to the batch should not be in the database until the batch is written")
+	}
+	if has, _ := batch.Has("test", []byte("answer")); !has {
+		t.Error("Has through the batch should see the writes to the batch")
+	}
+	if has, _ := db.Has("test", []byte("answer")); has {
+		t.Error("Has should not see writes to the batch until the batch is written")
+	}
+	if err := batch.Write(); err != nil {
+		t.Fatal(err)
+	}
+	batch.Close()
+	if !bytes.Equal(db.Get("test", []byte("answer")), []byte("42")) ||
+		!bytes.Equal(db.GetInt32("test", 7), []byte("seven")) {
+		t.Error("writes to the batch should be in the database once the batch is written")
+	}
+
+	discarded := db.NewBatch()
+	discarded.Put("test", []byte("question"), []byte("unknown"))
+	discarded.Close()
+	if db.Get("test", []byte("question")) != nil {
+		t.Error("writes to a batch closed without being written should be discarded")
+	}
+}
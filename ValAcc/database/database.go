@@ -9,36 +9,59 @@ package database
 //
 // To set a value in the database, call DB.Put(bucket string, key []byte, value []byte) error
 //
+// To check for a key, call DB.Has(bucket string, key []byte) (bool, error).  To walk the keys of a bucket,
+// call DB.Iterator or DB.ReverseIterator, or DB.ForEach.  DB.Count counts the keys of a bucket, and
+// DB.Delete removes a key.
+//
 // To get a value from the database, call DB.Get(bucket string, key []byte) (value []byte)_
 //
+// To write a set of values atomically, call DB.NewBatch(), Put the values into the Batch, then
+// call Batch.Write() and Batch.Close()
+//
 // see ValAcc/types/types.go for the constants for bucket names
 
 import (
+	"fmt"
+
 	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
 	dbm "github.com/tendermint/tm-db"
 )
 
 type DB struct {
-	DBHome   string
-	db2 dbm.DB
+	DBHome string
+	db2    dbm.DB
 }
 
-
-
 // We take an instance of the database, because we anticipate sometime in the future,
 // running multiple instances of the database.  This feature might not ever be used
 // for the ValAcc project, but it has been useful for factomd testing.
 func (d *DB) InitDB(db dbm.DB) {
-    d.db2 = db
+	d.db2 = db
 }
 
 //func (d *DB) Init(instance int) {
 //}
 
+// KeyVersion
+// The layout of the keys in the database.  The keys of version 0 just joined the bucket and the key, so a
+// bucket that is a prefix of another (like "node" and "node head") could produce the same keys.  Version 1
+// starts each key with the version, then the length of the bucket, the bucket, and then the key, so every
+// bucket is its own namespace.  A database written with version 0 keys must be migrated (see MigrateV0).
+const KeyVersion = 1
+
+// versionKey
+// Holds the KeyVersion of the database.  The leading zero keeps it clear of the keys of any version.
+var versionKey = []byte("\x00key version")
+
 // GetKey
 // Given a bucket and a key, return the combined key
 func GetKey(bucket string, key []byte) (CKey []byte) {
-	CKey = append(CKey, []byte(bucket)...)
+	if len(bucket) > 0xFF {
+		panic(fmt.Sprintf("bucket name %q is longer than 255 bytes", bucket))
+	}
+	CKey = make([]byte, 0, 2+len(bucket)+len(key))
+	CKey = append(CKey, KeyVersion, byte(len(bucket)))
+	CKey = append(CKey, bucket...)
 	CKey = append(CKey, key...)
 	return CKey
 }
@@ -65,7 +88,77 @@ func (d *DB) GetInt32(bucket string, ikey uint32) (value []byte) {
 // writing the key/value pair to the database.
 func (d *DB) Put(bucket string, key []byte, value []byte) error {
 	CKey := GetKey(bucket, key)
-	return d.db2.Set(CKey,value)
+	return d.db2.Set(CKey, value)
+}
+
+// Delete
+// Remove a key from the given bucket.  Deleting a key that isn't there is not an error.
+func (d *DB) Delete(bucket string, key []byte) error {
+	return d.db2.Delete(GetKey(bucket, key))
+}
+
+// Has
+// Returns true if the key is in the given bucket, without reading its value
+func (d *DB) Has(bucket string, key []byte) (bool, error) {
+	return d.db2.Has(GetKey(bucket, key))
+}
+
+// ForEach
+// Call fn with every key (without the bucket) and value in the given bucket, in key order, until fn
+// returns false.
+func (d *DB) ForEach(bucket string, fn func(key, value []byte) bool) error {
+	iter, err := d.Iterator(bucket, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer iter.Close()
+	for ; iter.Valid(); iter.Next() {
+		if !fn(iter.Key(), iter.Value()) {
+			break
+		}
+	}
+	return iter.Error()
+}
+
+// Count
+// Returns the count of keys in the given bucket
+func (d *DB) Count(bucket string) (int, error) {
+	return d.CountRange(bucket, nil, nil)
+}
+
+// CountRange
+// Returns the count of keys in the given bucket from start (inclusive) to end (exclusive).  A nil start
+// or end leaves that end of the range open (see Iterator).
+func (d *DB) CountRange(bucket string, start, end []byte) (int, error) {
+	iter, err := d.Iterator(bucket, start, end)
+	if err != nil {
+		return 0, err
+	}
+	defer iter.Close()
+	cnt := 0
+	for ; iter.Valid(); iter.Next() {
+		cnt++
+	}
+	return cnt, iter.Error()
+}
+
+// prefixEnd
+// Returns the first key after all the keys starting with the given prefix, or nil if there is none
+func prefixEnd(prefix []byte) []byte {
+	end := append([]byte{}, prefix...)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xFF {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	return nil
+}
+
+// Close
+// Close the underlying database.  No further Gets or Puts may be made once the database is closed.
+func (d *DB) Close() error {
+	return d.db2.Close()
 }
 
 // PutInt
@@ -5,6 +5,9 @@ import (
 	"io/ioutil"
 	"os"
 	"testing"
+
+	"github.com/dgraph-io/badger/v2"
+	dbm "github.com/tendermint/tm-db"
 )
 
 func TestDatabase(t *testing.T) {
@@ -45,8 +48,138 @@ func TestDatabase(t *testing.T) {
 
 func TestDatabase2(t *testing.T) {
 	db := new(DB)
-	db.Init(0)
+	db.InitDB(dbm.NewMemDB())
 	db.Put("test", []byte("answer"), []byte("42"))
 	answer := db.Get("test", []byte("answer"))
 	fmt.Println("The Answer is ", answer)
 }
+
+func TestForEach(t *testing.T) {
+	db := new(DB)
+	db.InitDB(dbm.NewMemDB())
+	for _, i := range []int{4, 0, 3, 1} {
+		db.Put("bucket", []byte{byte(i)}, []byte(fmt.Sprint("value ", i)))
+	}
+	db.Put("other", []byte{1}, []byte("not in the bucket"))
+	db.Put("bucker", []byte{1}, []byte("not in the bucket either"))
+
+	var keys []byte
+	err := db.ForEach("bucket", func(key, value []byte) bool {
+		if string(value) != fmt.Sprint("value ", key[0]) {
+			t.Errorf("wrong value %s for key %x", value, key)
+		}
+		keys = append(keys, key...)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(keys) != string([]byte{0, 1, 3, 4}) {
+		t.Errorf("expected the keys 0, 1, 3, 4 in order, got %v", keys)
+	}
+
+	cnt := 0
+	db.ForEach("bucket", func(key, value []byte) bool {
+		cnt++
+		return cnt < 2
+	})
+	if cnt != 2 {
+		t.Error("ForEach should stop when fn returns false")
+	}
+	if end := prefixEnd([]byte{1, 0xFF}); string(end) != string([]byte{2}) || prefixEnd([]byte{0xFF}) != nil {
+		t.Error("bad prefixEnd")
+	}
+}
+
+func TestBucketsDontCollide(t *testing.T) {
+	db := new(DB)
+	db.InitDB(dbm.NewMemDB())
+
+	// With the buckets simply joined to the keys, these were the same key
+	db.Put("node", []byte(" head1"), []byte("in node"))
+	db.Put("node head", []byte("1"), []byte("in node head"))
+	if string(db.Get("node", []byte(" head1"))) != "in node" || string(db.Get("node head", []byte("1"))) != "in node head" {
+		t.Error("a bucket that is a prefix of another should not share its keys")
+	}
+	cnt := 0
+	db.ForEach("node", func(key, value []byte) bool {
+		cnt++
+		return true
+	})
+	if cnt != 1 {
+		t.Errorf("expected 1 key in the node bucket, found %d", cnt)
+	}
+}
+
+func TestHasCount(t *testing.T) {
+	db := new(DB)
+	db.InitDB(dbm.NewMemDB())
+	for i := 0; i < 10; i++ {
+		db.Put("bucket", []byte{byte(i)}, []byte("value"))
+	}
+	db.Put("bucket", []byte{10}, []byte{}) // An empty value is still there
+	db.Put("buckets", []byte{1}, []byte("value"))
+
+	for _, key := range []byte{0, 9, 10} {
+		if has, err := db.Has("bucket", []byte{key}); err != nil || !has {
+			t.Errorf("key %d should be found: %v", key, err)
+		}
+	}
+	if has, err := db.Has("bucket", []byte{11}); err != nil || has {
+		t.Errorf("key 11 should not be found: %v", err)
+	}
+	if has, _ := db.Has("bucke", []byte("t\x01")); has {
+		t.Error("a key should not be found through a bucket that is a prefix of its own")
+	}
+
+	if cnt, err := db.Count("bucket"); err != nil || cnt != 11 {
+		t.Errorf("expected 11 keys, got %d %v", cnt, err)
+	}
+	if cnt, err := db.CountRange("bucket", []byte{3}, []byte{7}); err != nil || cnt != 4 {
+		t.Errorf("expected 4 keys from 3 up to 7, got %d %v", cnt, err)
+	}
+	db.Delete("bucket", []byte{5})
+	if cnt, _ := db.Count("bucket"); cnt != 10 {
+		t.Errorf("expected 10 keys after a delete, got %d", cnt)
+	}
+	if cnt, _ := db.Count("nothing"); cnt != 0 {
+		t.Errorf("expected no keys in an empty bucket, got %d", cnt)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	db := new(DB)
+	db.InitDB(dbm.NewMemDB())
+	for i := 0; i < 5; i++ {
+		db.Put("bucket", []byte{byte(i)}, []byte("value"))
+	}
+
+	if err := db.Delete("bucket", []byte{2}); err != nil {
+		t.Fatal(err)
+	}
+	if has, _ := db.Has("bucket", []byte{2}); has || db.Get("bucket", []byte{2}) != nil {
+		t.Error("a deleted key should not be found")
+	}
+	if err := db.Delete("bucket", []byte{9}); err != nil {
+		t.Error("deleting a key that isn't there is not an error")
+	}
+
+	batch := db.NewBatch()
+	defer batch.Close()
+	batch.Delete("bucket", []byte{3})
+	if has, _ := batch.Has("bucket", []byte{3}); has || batch.Get("bucket", []byte{3}) != nil {
+		t.Error("reads through the batch should not see a key deleted in the batch")
+	}
+	if has, _ := db.Has("bucket", []byte{3}); !has {
+		t.Error("a key deleted in the batch should be in the database until the batch is written")
+	}
+	if err := batch.Write(); err != nil {
+		t.Fatal(err)
+	}
+	if has, _ := db.Has("bucket", []byte{3}); has {
+		t.Error("a key deleted in the batch should be gone once the batch is written")
+	}
+	if cnt, _ := db.Count("bucket"); cnt != 3 {
+		t.Errorf("expected 3 keys after the deletes, got %d", cnt)
+	}
+}
@@ -0,0 +1,95 @@
+package database
+
+import (
+	dbm "github.com/tendermint/tm-db"
+)
+
+// Iterator
+// Walks the keys of one bucket in order, forward or in reverse (see DB.Iterator and DB.ReverseIterator).
+// Keys are returned without the bucket.  The caller must Close the Iterator when done.  The database should
+// not be written while an Iterator is open, as some backends block writes until it is closed.
+//
+//	iter, err := db.Iterator(types.NodeHead, nil, nil)
+//	if err != nil { ... }
+//	defer iter.Close()
+//	for ; iter.Valid(); iter.Next() {
+//	    chainID, nodeHash := iter.Key(), iter.Value()
+//	}
+//	return iter.Error()
+type Iterator struct {
+	iter   dbm.Iterator // The underlying iterator over the combined keys
+	prefix int          // Length of the bucket prefix stripped from the keys
+}
+
+// Iterator
+// Iterate over the keys in the bucket from start (inclusive) to end (exclusive) in ascending order.  A nil
+// start begins at the first key in the bucket, and a nil end runs to the last.
+func (d *DB) Iterator(bucket string, start, end []byte) (*Iterator, error) {
+	from, to := bucketRange(bucket, start, end)
+	iter, err := d.db2.Iterator(from, to)
+	if err != nil {
+		return nil, err
+	}
+	return &Iterator{iter: iter, prefix: len(GetKey(bucket, nil))}, nil
+}
+
+// ReverseIterator
+// Iterate over the keys in the bucket from start (inclusive) to end (exclusive) in descending order, i.e.
+// from the last key before end back to start.  A nil start runs back to the first key in the bucket, and a
+// nil end begins at the last.
+func (d *DB) ReverseIterator(bucket string, start, end []byte) (*Iterator, error) {
+	from, to := bucketRange(bucket, start, end)
+	iter, err := d.db2.ReverseIterator(from, to)
+	if err != nil {
+		return nil, err
+	}
+	return &Iterator{iter: iter, prefix: len(GetKey(bucket, nil))}, nil
+}
+
+// bucketRange
+// Convert a range of keys in a bucket into the range of combined keys in the database
+func bucketRange(bucket string, start, end []byte) (from, to []byte) {
+	from = GetKey(bucket, start)
+	if end == nil {
+		to = prefixEnd(GetKey(bucket, nil))
+	} else {
+		to = GetKey(bucket, end)
+	}
+	return from, to
+}
+
+// Valid
+// Returns true while the Iterator is positioned at a key.  Once false, the Iterator is done.
+func (i *Iterator) Valid() bool {
+	return i.iter.Valid()
+}
+
+// Next
+// Move to the next key
+func (i *Iterator) Next() {
+	i.iter.Next()
+}
+
+// Key
+// Returns the current key, without the bucket.  The key is only valid until Next is called.
+func (i *Iterator) Key() []byte {
+	return i.iter.Key()[i.prefix:]
+}
+
+// Value
+// Returns the current value.  The value is only valid until Next is called.
+func (i *Iterator) Value() []byte {
+	return i.iter.Value()
+}
+
+// Error
+// Returns any error met while iterating
+func (i *Iterator) Error() error {
+	return i.iter.Error()
+}
+
+// Close
+// Release the Iterator
+func (i *Iterator) Close() error {
+	return i.iter.Close()
+}
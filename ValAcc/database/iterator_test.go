@@ -0,0 +1,70 @@
+package database
+
+import (
+	"testing"
+
+	dbm "github.com/tendermint/tm-db"
+)
+
+// collectKeys
+// Walk the iterator, returning the first byte of every key
+func collectKeys(t *testing.T, iter *Iterator, err error) (keys []byte) {
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iter.Close()
+	for ; iter.Valid(); iter.Next() {
+		if len(iter.Key()) != 1 || string(iter.Value()) != string([]byte{'v', iter.Key()[0]}) {
+			t.Errorf("wrong key %x or value %q", iter.Key(), iter.Value())
+		}
+		keys = append(keys, iter.Key()...)
+	}
+	if err := iter.Error(); err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+func TestIterator(t *testing.T) {
+	db := new(DB)
+	db.InitDB(dbm.NewMemDB())
+	for i := byte(0); i < 5; i++ {
+		db.Put("bucket", []byte{i}, []byte{'v', i})
+		db.Put("bucket2", []byte{i}, []byte("another bucket"))
+		db.Put("bucke", []byte{i}, []byte("yet another bucket"))
+	}
+	db.Put("bucket", []byte{0xFF}, []byte{'v', 0xFF})
+
+	for _, test := range []struct {
+		name       string
+		reverse    bool
+		start, end []byte
+		expected   []byte
+	}{
+		{"everything", false, nil, nil, []byte{0, 1, 2, 3, 4, 0xFF}},
+		{"from 2", false, []byte{2}, nil, []byte{2, 3, 4, 0xFF}},
+		{"up to 2", false, nil, []byte{2}, []byte{0, 1}},
+		{"1 up to 4", false, []byte{1}, []byte{4}, []byte{1, 2, 3}},
+		{"everything in reverse", true, nil, nil, []byte{0xFF, 4, 3, 2, 1, 0}},
+		{"from 2 in reverse", true, []byte{2}, nil, []byte{0xFF, 4, 3, 2}},
+		{"1 up to 4 in reverse", true, []byte{1}, []byte{4}, []byte{3, 2, 1}},
+		{"an empty range", false, []byte{3}, []byte{3}, nil},
+	} {
+		var keys []byte
+		if test.reverse {
+			iter, err := db.ReverseIterator("bucket", test.start, test.end)
+			keys = collectKeys(t, iter, err)
+		} else {
+			iter, err := db.Iterator("bucket", test.start, test.end)
+			keys = collectKeys(t, iter, err)
+		}
+		if string(keys) != string(test.expected) {
+			t.Errorf("%s: expected the keys %v, got %v", test.name, test.expected, keys)
+		}
+	}
+
+	iter, err := db.Iterator("empty", nil, nil)
+	if keys := collectKeys(t, iter, err); len(keys) != 0 {
+		t.Error("an empty bucket should have no keys")
+	}
+}
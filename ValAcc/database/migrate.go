@@ -0,0 +1,178 @@
+package database
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
+)
+
+// keyLengths
+// The length of the keys in each bucket.  With version 0 keys, the bucket and the key can only be told
+// apart by the length of what follows the bucket name, i.e. a "node head" key is "node head" and a 32 byte
+// ChainID, not "node" and the 37 bytes " head...".  A new bucket must be added here, or the keys in it fail
+// verifyKeys.
+var keyLengths = map[string]int{
+	types.NodeFirst:            32,
+	types.NodeNext:             32,
+	types.NodeHead:             32,
+	types.Entry:                32,
+	types.EntryNode:            32,
+	types.DirectoryBlockHeight: 4,
+	types.Node:                 32,
+	types.MDState:              32,
+	types.MDRootNode:           32,
+	types.ChainMoves:           32,
+	types.Anchor:               32,
+	types.GlobalRoot:           4,
+	types.ShardStrategy:        len("strategy"), // The only key of the router's shard strategy
+}
+
+// migrateChunk
+// The count of records moved to their new keys in each batch
+const migrateChunk = 10000
+
+// splitV0Key
+// Split a version 0 key into its bucket and key.  Returns an error if the key isn't of any known bucket,
+// or could be of more than one.
+func splitV0Key(v0Key []byte) (bucket string, key []byte, err error) {
+	found := 0
+	for b, keyLen := range keyLengths {
+		if bytes.HasPrefix(v0Key, []byte(b)) && len(v0Key)-len(b) == keyLen {
+			bucket, key = b, v0Key[len(b):]
+			found++
+		}
+	}
+	if found != 1 {
+		return "", nil, errors.New(fmt.Sprintf("key %x is in %d known buckets", v0Key, found))
+	}
+	return bucket, key, nil
+}
+
+// MigrateV0
+// Rewrite every version 0 key in the database as a key of the current KeyVersion, then check every record
+// in the database, and mark the database with the KeyVersion.  Returns the count of records migrated.
+//
+// Each chunk of records is moved to its new keys in one batch, which deletes the old keys, so if the
+// migration is interrupted, running it again carries on where it left off.  A key that can't be split into
+// its bucket and key stops the migration before the chunk holding it is written.
+func MigrateV0(d *DB) (migrated int, err error) {
+	version, err := d.keyVersion()
+	if err != nil {
+		return 0, err
+	}
+	if version == KeyVersion {
+		return 0, nil // Migrated already
+	}
+	if version != 0 {
+		return 0, errors.New(fmt.Sprintf("can't migrate keys of version %d", version))
+	}
+
+	// Every version 0 key starts with a bucket name, so is past the version 1 keys (0x01...) and the
+	// versionKey (0x00...)
+	start := []byte{KeyVersion + 1}
+	for {
+		var oldKeys, newKeys, values [][]byte
+		iter, err := d.db2.Iterator(start, nil)
+		if err != nil {
+			return migrated, err
+		}
+		for ; iter.Valid() && len(oldKeys) < migrateChunk; iter.Next() {
+			bucket, key, err := splitV0Key(iter.Key())
+			if err != nil {
+				iter.Close()
+				return migrated, err
+			}
+			oldKeys = append(oldKeys, append([]byte{}, iter.Key()...))
+			newKeys = append(newKeys, GetKey(bucket, key))
+			values = append(values, append([]byte{}, iter.Value()...))
+		}
+		err = iter.Error()
+		iter.Close() // Some backends don't allow writes while iterating
+		if err != nil {
+			return migrated, err
+		}
+		if len(oldKeys) == 0 {
+			break
+		}
+
+		batch := d.db2.NewBatch()
+		for i := range oldKeys {
+			if err := batch.Set(newKeys[i], values[i]); err != nil {
+				batch.Close()
+				return migrated, err
+			}
+			if err := batch.Delete(oldKeys[i]); err != nil {
+				batch.Close()
+				return migrated, err
+			}
+		}
+		err = batch.WriteSync()
+		batch.Close()
+		if err != nil {
+			return migrated, err
+		}
+
+		for i := range oldKeys {
+			value, err := d.db2.Get(newKeys[i])
+			if err != nil {
+				return migrated, err
+			}
+			if !bytes.Equal(value, values[i]) {
+				return migrated, errors.New(fmt.Sprintf("the record at %x did not survive the move to %x", oldKeys[i], newKeys[i]))
+			}
+		}
+		migrated += len(oldKeys)
+	}
+
+	if err := d.verifyKeys(); err != nil {
+		return migrated, err
+	}
+	return migrated, d.db2.SetSync(versionKey, []byte{KeyVersion})
+}
+
+// verifyKeys
+// Check that every key in the database is the versionKey, or a key of the current KeyVersion in a known
+// bucket with a key of the right length
+func (d *DB) verifyKeys() error {
+	iter, err := d.db2.Iterator(nil, nil)
+	if err != nil {
+		return err
+	}
+	defer iter.Close()
+	for ; iter.Valid(); iter.Next() {
+		key := iter.Key()
+		if bytes.Equal(key, versionKey) {
+			continue
+		}
+		if len(key) < 2 || key[0] != KeyVersion || len(key) < 2+int(key[1]) {
+			return errors.New(fmt.Sprintf("key %x is not a version %d key", key, KeyVersion))
+		}
+		bucket := string(key[2 : 2+int(key[1])])
+		keyLen, ok := keyLengths[bucket]
+		if !ok || len(key)-2-len(bucket) != keyLen {
+			return errors.New(fmt.Sprintf("key %x is not a key of a known bucket", key))
+		}
+	}
+	return iter.Error()
+}
+
+// Migrate
+// Open the database with the given backend, directory and name (see Open), and migrate it to the current
+// KeyVersion.  Returns the count of records migrated.  Returns an error if the database doesn't exist, rather
+// than creating an empty one.
+func Migrate(backend, dir, name string) (int, error) {
+	if err := Exists(backend, dir, name); err != nil {
+		return 0, err
+	}
+	d, err := open(backend, dir, name)
+	if err != nil {
+		return 0, err
+	}
+	migrated, err := MigrateV0(d)
+	if cErr := d.Close(); cErr != nil && err == nil {
+		err = cErr
+	}
+	return migrated, err
+}
@@ -0,0 +1,117 @@
+package database
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
+	dbm "github.com/tendermint/tm-db"
+)
+
+// v0Record
+// A record as it was written with version 0 keys
+type v0Record struct {
+	bucket string
+	key    []byte
+	value  []byte
+}
+
+// getV0Records
+// Build records in every bucket, including the buckets that are prefixes of others
+func getV0Records(cnt int) (records []v0Record) {
+	for i := 0; i < cnt; i++ {
+		hash := sha256.Sum256([]byte(fmt.Sprint("key ", i)))
+		value := []byte(fmt.Sprint("value ", i))
+		for bucket, keyLen := range keyLengths {
+			key := hash[:keyLen]
+			if bucket == types.ShardStrategy {
+				key = []byte("strategy")
+			}
+			records = append(records, v0Record{bucket, key, append([]byte(bucket), value...)})
+		}
+	}
+	return records
+}
+
+func TestMigrateV0(t *testing.T) {
+	dir := t.TempDir()
+	d, err := open("goleveldb", dir, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := getV0Records(3000) // More than a chunk
+	for _, r := range records {
+		if err := d.db2.Set(append([]byte(r.bucket), r.key...), r.value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	d.Close()
+
+	migrated, err := Migrate("goleveldb", dir, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := len(records) - 2999 // Every record wrote the one shard strategy key
+	if migrated != expected {
+		t.Errorf("expected %d records migrated, got %d", expected, migrated)
+	}
+
+	d, err = Open("goleveldb", dir, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	for _, r := range records {
+		if r.bucket == types.ShardStrategy {
+			continue
+		}
+		if !bytes.Equal(d.Get(r.bucket, r.key), r.value) {
+			t.Fatalf("the record in %q at %x was lost", r.bucket, r.key)
+		}
+	}
+	if migrated, err := MigrateV0(d); err != nil || migrated != 0 {
+		t.Errorf("migrating again should do nothing, got %d %v", migrated, err)
+	}
+}
+
+func TestMigrateMissing(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "typo")
+	if _, err := Migrate("goleveldb", dir, "test"); err == nil {
+		t.Error("migrating a database that doesn't exist should fail")
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Error("migrating a database that doesn't exist should not create one")
+	}
+}
+
+func TestMigrateUnknownKey(t *testing.T) {
+	d := new(DB)
+	d.InitDB(dbm.NewMemDB())
+	d.db2.Set(append([]byte(types.Node), make([]byte, 32)...), []byte("good"))
+	d.db2.Set([]byte("no such bucket"), []byte("bad"))
+	if _, err := MigrateV0(d); err == nil {
+		t.Error("a key in no known bucket should stop the migration")
+	}
+	if v, _ := d.db2.Get(append([]byte(types.Node), make([]byte, 32)...)); !bytes.Equal(v, []byte("good")) {
+		t.Error("nothing should be migrated from the chunk holding a bad key")
+	}
+
+	for _, test := range []struct {
+		key    string
+		bucket string
+	}{
+		{types.Node + string(make([]byte, 32)), types.Node},
+		{types.NodeHead + string(make([]byte, 32)), types.NodeHead},
+		{types.Entry + string(make([]byte, 32)), types.Entry},
+		{types.EntryNode + string(make([]byte, 32)), types.EntryNode},
+	} {
+		bucket, key, err := splitV0Key([]byte(test.key))
+		if err != nil || bucket != test.bucket || len(key) != 32 {
+			t.Errorf("expected %q to split into %q and 32 bytes, got %q, %d bytes and %v", test.key, test.bucket, bucket, len(key), err)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	dbm "github.com/tendermint/tm-db"
+)
+
+// Open
+// Open (or create) the database with the given name in the given directory, using the given tm-db backend:
+// "goleveldb", "cleveldb", "badgerdb", "boltdb", "rocksdb" or "memdb".  All but goleveldb and memdb need
+// the matching build tag (see build.sh).  A memdb database is only held in memory, and the directory is
+// ignored.  Returns an error if the backend isn't built in, the database can't be opened, or the database
+// holds keys of an older KeyVersion (see MigrateV0).
+func Open(backend, dir, name string) (*DB, error) {
+	d, err := open(backend, dir, name)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.checkLayout(); err != nil {
+		d.Close()
+		return nil, err
+	}
+	return d, nil
+}
+
+// OpenReadOnly
+// Open an existing database without writing to it, so it can be read while the accumulator holding it is
+// stopped, or a copy of it can be inspected.  Only the goleveldb backend can be opened read only.  Returns
+// an error if the database doesn't exist, or doesn't hold keys of the current KeyVersion.
+func OpenReadOnly(backend, dir, name string) (*DB, error) {
+	if dbm.BackendType(backend) != dbm.GoLevelDBBackend {
+		return nil, errors.New(fmt.Sprintf("the %s backend can't be opened read only", backend))
+	}
+	if err := Exists(backend, dir, name); err != nil {
+		return nil, err
+	}
+	tmDB, err := dbm.NewGoLevelDBWithOpts(name, dir, &opt.Options{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	d := new(DB)
+	d.DBHome = dir
+	d.InitDB(tmDB)
+	version, err := d.keyVersion()
+	if err == nil && version != KeyVersion {
+		err = errors.New(fmt.Sprintf("the database in %s has version %d keys, and this build only knows version %d",
+			dir, version, KeyVersion))
+	}
+	if err != nil {
+		d.Close()
+		return nil, err
+	}
+	return d, nil
+}
+
+// Exists
+// Returns an error if there is no database with the given backend and name in the directory.  A memdb
+// database never exists before it is opened.
+func Exists(backend, dir, name string) error {
+	path := filepath.Join(dir, name+".db")
+	switch dbm.BackendType(backend) {
+	case dbm.MemDBBackend:
+		return errors.New("a memdb database only exists while it is open")
+	case dbm.BadgerDBBackend:
+		path = filepath.Join(dir, name) // Badger has no database names, so the name is the directory
+	}
+	if _, err := os.Stat(path); err != nil {
+		return errors.New(fmt.Sprintf("no %s database %s: %v", backend, path, err))
+	}
+	return nil
+}
+
+// open
+// Open the database without looking at its layout
+func open(backend, dir, name string) (*DB, error) {
+	if dbm.BackendType(backend) != dbm.MemDBBackend {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+	}
+	tmDB, err := dbm.NewDB(name, dbm.BackendType(backend), dir)
+	if err != nil {
+		return nil, err
+	}
+	d := new(DB)
+	d.DBHome = dir
+	d.InitDB(tmDB)
+	return d, nil
+}
+
+// checkLayout
+// Make sure the keys in the database are of the current KeyVersion.  A new database is marked with the
+// current KeyVersion.  A database with data, but no KeyVersion, was written with version 0 keys.
+func (d *DB) checkLayout() error {
+	version, err := d.keyVersion()
+	if err != nil {
+		return err
+	}
+	switch version {
+	case KeyVersion:
+		return nil
+	case 0:
+		empty, err := d.isEmpty()
+		if err != nil {
+			return err
+		}
+		if !empty {
+			return errors.New(fmt.Sprintf("the database in %s has version 0 keys, and must be migrated to version %d (see cmd/migratedb)",
+				d.DBHome, KeyVersion))
+		}
+		return d.db2.SetSync(versionKey, []byte{KeyVersion})
+	default:
+		return errors.New(fmt.Sprintf("the database in %s has version %d keys, and this build only knows version %d",
+			d.DBHome, version, KeyVersion))
+	}
+}
+
+// keyVersion
+// Returns the KeyVersion marked in the database, or 0 if none is
+func (d *DB) keyVersion() (byte, error) {
+	version, err := d.db2.Get(versionKey)
+	if err != nil {
+		return 0, err
+	}
+	if len(version) != 1 {
+		return 0, nil
+	}
+	return version[0], nil
+}
+
+// isEmpty
+// Returns true if the database holds no keys at all
+func (d *DB) isEmpty() (bool, error) {
+	iter, err := d.db2.Iterator(nil, nil)
+	if err != nil {
+		return false, err
+	}
+	defer iter.Close()
+	return !iter.Valid(), iter.Error()
+}
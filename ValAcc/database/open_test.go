@@ -0,0 +1,94 @@
+package database
+
+import (
+	"testing"
+)
+
+func TestOpen(t *testing.T) {
+	dir := t.TempDir()
+	d, err := Open("goleveldb", dir, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Put("bucket", []byte("key"), []byte("value")); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err = Open("goleveldb", dir, "test") // Opened again, it holds the current KeyVersion
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(d.Get("bucket", []byte("key"))) != "value" {
+		t.Error("the value written should be found again")
+	}
+	d.Close()
+
+	if _, err := Open("nosuchdb", dir, "test"); err == nil {
+		t.Error("an unknown backend should fail")
+	}
+	if _, err := Open("memdb", "", "test"); err != nil {
+		t.Errorf("a memdb database needs no directory: %v", err)
+	}
+}
+
+func TestOpenOldLayout(t *testing.T) {
+	dir := t.TempDir()
+	d, err := open("goleveldb", dir, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.db2.Set([]byte("node head0123456789abcdef0123456789abcdef"), []byte("v0")) // A version 0 key
+	d.Close()
+
+	if _, err := Open("goleveldb", dir, "test"); err == nil {
+		t.Error("a database of version 0 keys should not open until it is migrated")
+	}
+
+	d, _ = open("goleveldb", dir, "test")
+	d.db2.Set(versionKey, []byte{KeyVersion + 1})
+	d.Close()
+	if _, err := Open("goleveldb", dir, "test"); err == nil {
+		t.Error("a database of a later version should not open")
+	}
+}
+
+func TestOpenReadOnly(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := OpenReadOnly("goleveldb", dir, "test"); err == nil {
+		t.Error("a database that doesn't exist should not open")
+	}
+
+	d, err := Open("goleveldb", dir, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Put("bucket", []byte("key"), []byte("value"))
+	d.Close()
+
+	d, err = OpenReadOnly("goleveldb", dir, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(d.Get("bucket", []byte("key"))) != "value" {
+		t.Error("the value written should be found read only")
+	}
+	if err := d.Put("bucket", []byte("key"), []byte("changed")); err == nil {
+		t.Error("a database opened read only should refuse writes")
+	}
+	d.Close()
+
+	if _, err := OpenReadOnly("memdb", dir, "test"); err == nil {
+		t.Error("only goleveldb can be opened read only")
+	}
+
+	old := t.TempDir()
+	d, _ = open("goleveldb", old, "test")
+	d.db2.Set([]byte("node head0123456789abcdef0123456789abcdef"), []byte("v0")) // A version 0 key
+	d.Close()
+	if _, err := OpenReadOnly("goleveldb", old, "test"); err == nil {
+		t.Error("a database of version 0 keys should not open")
+	}
+}
@@ -0,0 +1,249 @@
+package merkleDag
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+	"sort"
+
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
+)
+
+// BatchProofVersion
+// Version of the binary encoding of a BatchProof.
+const BatchProofVersion = 1
+
+// BatchProof
+// Proves any number of entries in one MD against the MD's root.  Where a set of MDReceipts repeats the
+// interior hashes shared by the entries, the BatchProof carries each hash needed only once, and carries no
+// hash that can be computed from the entries themselves.
+//
+// The MD has the shape of a Merkle tree where the hashes [lo, hi) are split at the largest power of two
+// less than hi-lo, the left side combined with the right.  Validate walks that tree from the root, and every
+// subtree holding none of the entries takes the next hash from Hashes.  So Hashes holds the roots of the
+// largest subtrees holding no entries, from left to right.
+type BatchProof struct {
+	Count   uint64       // Count of all the hashes in the MD, including any added before a restored state
+	Indexes []uint64     // Position of each entry in the MD, in ascending order
+	Entries []types.Hash // The entry hashes proven, in the order of Indexes
+	Hashes  []types.Hash // Roots of the subtrees holding none of the entries, left to right
+	MDRoot  types.Hash   // MDRoot of the MD
+}
+
+// splitSize
+// The size of the left side of a subtree of the given size (2 or more); the largest power of two less than it
+func splitSize(size uint64) uint64 {
+	return uint64(1) << uint(bits.Len64(size-1)-1)
+}
+
+// subtreeRoot
+// Compute the root of the hashes [lo, hi) of the MD.  Hashes added before the state of the MD was restored
+// are no longer known, but the complete subtrees of them in the StartState are, and so is any range ending
+// at the StartCount.
+func (m *MD) subtreeRoot(lo, hi uint64) (*types.Hash, error) {
+	size := hi - lo
+	if hi <= m.StartCount && size&(size-1) == 0 { // A complete subtree from before the restored state
+		level := bits.TrailingZeros64(size)
+		if level >= len(m.StartState) || m.StartState[level] == nil || lo != m.StartCount>>uint(level+1)<<uint(level+1) {
+			return nil, errors.New(fmt.Sprintf("hashes [%d, %d) are not part of the MD state", lo, hi))
+		}
+		return m.StartState[level].Copy(), nil
+	}
+	if size == 1 {
+		return m.HashList[lo-m.StartCount].Copy(), nil
+	}
+	k := splitSize(size)
+	left, err := m.subtreeRoot(lo, lo+k)
+	if err != nil {
+		return nil, err
+	}
+	right, err := m.subtreeRoot(lo+k, hi)
+	if err != nil {
+		return nil, err
+	}
+	return left.Combine(*right), nil
+}
+
+// BuildBatchProof
+// Build a proof of the given entries against the root of the MD.  The entries must be in the MD's HashList.
+// Duplicate entries are proven once.  Returns an error if any entry is not found.
+func BuildBatchProof(MerkleDag MD, entries []types.Hash) (*BatchProof, error) {
+	if len(entries) == 0 {
+		return nil, errors.New("no entries to prove")
+	}
+	positions := make(map[types.Hash]uint64, len(MerkleDag.HashList))
+	for i := len(MerkleDag.HashList) - 1; i >= 0; i-- { // Going backwards, so the first instance of a hash wins
+		positions[MerkleDag.HashList[i]] = MerkleDag.StartCount + uint64(i)
+	}
+	found := make(map[uint64]bool, len(entries))
+	bp := new(BatchProof)
+	for _, e := range entries {
+		index, ok := positions[e]
+		if !ok {
+			return nil, errors.New(fmt.Sprintf("entry %x is not in the MD", e))
+		}
+		if !found[index] {
+			found[index] = true
+			bp.Indexes = append(bp.Indexes, index)
+		}
+	}
+	sort.Slice(bp.Indexes, func(i, j int) bool { return bp.Indexes[i] < bp.Indexes[j] })
+	for _, index := range bp.Indexes {
+		bp.Entries = append(bp.Entries, MerkleDag.HashList[index-MerkleDag.StartCount])
+	}
+	bp.Count = MerkleDag.Count()
+
+	var build func(lo, hi uint64, indexes []uint64) (*types.Hash, error)
+	build = func(lo, hi uint64, indexes []uint64) (*types.Hash, error) {
+		if len(indexes) == 0 { // Nothing to prove here, so the proof carries the root of this subtree
+			h, err := MerkleDag.subtreeRoot(lo, hi)
+			if err != nil {
+				return nil, err
+			}
+			bp.Hashes = append(bp.Hashes, *h)
+			return h, nil
+		}
+		if hi-lo == 1 { // One of our entries
+			return MerkleDag.HashList[lo-MerkleDag.StartCount].Copy(), nil
+		}
+		mid := lo + splitSize(hi-lo)
+		split := sort.Search(len(indexes), func(i int) bool { return indexes[i] >= mid })
+		left, err := build(lo, mid, indexes[:split])
+		if err != nil {
+			return nil, err
+		}
+		right, err := build(mid, hi, indexes[split:])
+		if err != nil {
+			return nil, err
+		}
+		return left.Combine(*right), nil
+	}
+	root, err := build(0, bp.Count, bp.Indexes)
+	if err != nil {
+		return nil, err
+	}
+	bp.MDRoot = *root
+	return bp, nil
+}
+
+// Validate
+// Check that the Hashes and the Entries at their Indexes combine to the MDRoot, using every hash in the proof.
+func (bp *BatchProof) Validate() bool {
+	if len(bp.Indexes) == 0 || len(bp.Indexes) != len(bp.Entries) {
+		return false
+	}
+	for i, index := range bp.Indexes {
+		if index >= bp.Count || (i > 0 && index <= bp.Indexes[i-1]) {
+			return false
+		}
+	}
+	hashes := bp.Hashes
+	var verify func(lo, hi uint64, indexes []uint64, entries []types.Hash) (types.Hash, bool)
+	verify = func(lo, hi uint64, indexes []uint64, entries []types.Hash) (types.Hash, bool) {
+		if len(indexes) == 0 { // Take the next hash from the proof
+			if len(hashes) == 0 {
+				return types.Hash{}, false
+			}
+			h := hashes[0]
+			hashes = hashes[1:]
+			return h, true
+		}
+		if hi-lo == 1 {
+			return entries[0], true
+		}
+		mid := lo + splitSize(hi-lo)
+		split := sort.Search(len(indexes), func(i int) bool { return indexes[i] >= mid })
+		left, ok := verify(lo, mid, indexes[:split], entries[:split])
+		if !ok {
+			return left, false
+		}
+		right, ok := verify(mid, hi, indexes[split:], entries[split:])
+		if !ok {
+			return right, false
+		}
+		return *left.Combine(right), true
+	}
+	root, ok := verify(0, bp.Count, bp.Indexes, bp.Entries)
+	return ok && len(hashes) == 0 && root == bp.MDRoot
+}
+
+// Marshal
+// The compact binary encoding of a BatchProof.  Counts and indexes are types varints; each index is
+// encoded as the difference from the index before it.
+//
+//	version      byte      BatchProofVersion
+//	Count        varint
+//	len(Entries) varint
+//	  Index      varint    Indexes[i] - Indexes[i-1] (Indexes[0] for the first)
+//	  Entry      [32]byte
+//	len(Hashes)  varint
+//	  Hash       [32]byte
+//	MDRoot       [32]byte
+func (bp *BatchProof) Marshal() (data []byte) {
+	data = append(data, BatchProofVersion)
+	data = append(data, types.EncodeVarIntGoBytes(bp.Count)...)
+	data = append(data, types.EncodeVarIntGoBytes(uint64(len(bp.Entries)))...)
+	var last uint64
+	for i, e := range bp.Entries {
+		data = append(data, types.EncodeVarIntGoBytes(bp.Indexes[i]-last)...)
+		data = append(data, e.Bytes()...)
+		last = bp.Indexes[i]
+	}
+	data = append(data, types.EncodeVarIntGoBytes(uint64(len(bp.Hashes)))...)
+	for _, h := range bp.Hashes {
+		data = append(data, h.Bytes()...)
+	}
+	data = append(data, bp.MDRoot.Bytes()...)
+	return data
+}
+
+// Unmarshal
+// Extract a BatchProof from its binary encoding.  Returns an error if the unmarshal fails, or the length of
+// the data consumed and a nil.
+func (bp *BatchProof) Unmarshal(data []byte) (dataConsumed int, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New(fmt.Sprintf("BatchProof Failed to unmarshal %v", r))
+		}
+	}()
+	d := data
+	if data[0] != BatchProofVersion {
+		return 0, errors.New(fmt.Sprintf("BatchProof version %d is not supported", data[0]))
+	}
+	data = data[1:]
+	varint := func() uint64 { // Every varint is followed by more data, so a varint running off the end is bad
+		v, rest := types.DecodeVarInt(data)
+		if len(rest) == 0 || data[len(data)-len(rest)-1] >= 0x80 {
+			panic("truncated varint")
+		}
+		data = rest
+		return v
+	}
+	bp.Count = varint()
+	numEntries := varint()
+	if numEntries > uint64(len(data)/33) {
+		return 0, errors.New(fmt.Sprintf("BatchProof claims %d entries, more than the data holds", numEntries))
+	}
+	bp.Indexes = make([]uint64, 0, numEntries)
+	bp.Entries = make([]types.Hash, 0, numEntries)
+	var last uint64
+	for i := uint64(0); i < numEntries; i++ {
+		last += varint()
+		var e types.Hash
+		data = e.Extract(data)
+		bp.Indexes = append(bp.Indexes, last)
+		bp.Entries = append(bp.Entries, e)
+	}
+	numHashes := varint()
+	if numHashes > uint64(len(data)/32) {
+		return 0, errors.New(fmt.Sprintf("BatchProof claims %d hashes, more than the data holds", numHashes))
+	}
+	bp.Hashes = make([]types.Hash, 0, numHashes)
+	for i := uint64(0); i < numHashes; i++ {
+		var h types.Hash
+		data = h.Extract(data)
+		bp.Hashes = append(bp.Hashes, h)
+	}
+	data = bp.MDRoot.Extract(data)
+	return len(d) - len(data), nil
+}
@@ -0,0 +1,171 @@
+package merkleDag
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
+)
+
+func TestBatchProof(t *testing.T) {
+	hashes := getTestHashes(70)
+	r := rand.New(rand.NewSource(1))
+	for start := 0; start < 20; start += 7 { // Prove hashes added after the MD state was restored, too
+		for n := 1; n < 50; n++ {
+			full := new(MD)
+			for _, h := range hashes[:start] {
+				full.AddToChain(h)
+			}
+			md, err := NewMD(full.Count(), full.CompressState())
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, h := range hashes[start : start+n] {
+				md.AddToChain(h)
+			}
+
+			var entries []types.Hash
+			for _, h := range md.HashList {
+				if r.Intn(3) == 0 {
+					entries = append(entries, h)
+				}
+			}
+			if len(entries) == 0 {
+				entries = append(entries, md.HashList[n-1])
+			}
+			entries = append(entries, entries[0]) // Duplicates are proven once
+
+			bp, err := BuildBatchProof(*md, entries)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bp.MDRoot != *md.GetMDRoot() {
+				t.Fatalf("start %d n %d: the batch proof root should be the MDRoot", start, n)
+			}
+			if !bp.Validate() {
+				t.Fatalf("start %d n %d: the batch proof should validate", start, n)
+			}
+			if len(bp.Entries) != len(entries)-1 {
+				t.Errorf("start %d n %d: expected %d entries, got %d", start, n, len(entries)-1, len(bp.Entries))
+			}
+
+			data := bp.Marshal()
+			bp2 := new(BatchProof)
+			if _, err := bp2.Unmarshal(data); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(bp2.Marshal(), data) || !bp2.Validate() {
+				t.Errorf("start %d n %d: the batch proof did not survive a round trip", start, n)
+			}
+			for cut := 0; cut < len(data); cut++ {
+				if _, err := new(BatchProof).Unmarshal(data[:cut]); err == nil {
+					t.Fatalf("start %d n %d: a batch proof truncated to %d bytes should not unmarshal", start, n, cut)
+				}
+			}
+
+			bp.Entries[0][0]++
+			if bp.Validate() {
+				t.Errorf("start %d n %d: a batch proof with a bad entry should not validate", start, n)
+			}
+			bp.Entries[0][0]--
+			bp.Indexes[0], bp.Count = bp.Indexes[0]+bp.Count, bp.Count*2
+			if bp.Validate() {
+				t.Errorf("start %d n %d: a batch proof with bad indexes should not validate", start, n)
+			}
+		}
+	}
+
+	md := new(MD)
+	for _, h := range hashes[:10] {
+		md.AddToChain(h)
+	}
+	if _, err := BuildBatchProof(*md, hashes[10:11]); err == nil {
+		t.Error("an entry not in the MD should not be proven")
+	}
+	if _, err := BuildBatchProof(*md, nil); err == nil {
+		t.Error("a batch proof needs entries")
+	}
+	bp, _ := BuildBatchProof(*md, hashes[2:4])
+	bp.Hashes = append(bp.Hashes, hashes[0])
+	if bp.Validate() {
+		t.Error("a batch proof with extra hashes should not validate")
+	}
+}
+
+// BenchmarkBatchProof
+// Compare the size of one batch proof with the total size of individual receipts for the same entries.
+func BenchmarkBatchProof(b *testing.B) {
+	md := new(MD)
+	for _, h := range getTestHashes(10000) {
+		md.AddToChain(h)
+	}
+	r := rand.New(rand.NewSource(1))
+	var entries []types.Hash
+	for _, i := range r.Perm(len(md.HashList))[:1000] {
+		entries = append(entries, md.HashList[i])
+	}
+
+	b.Run("batch", func(b *testing.B) {
+		var size int
+		for i := 0; i < b.N; i++ {
+			bp, err := BuildBatchProof(*md, entries)
+			if err != nil || !bp.Validate() {
+				b.Fatal("batch proof failed")
+			}
+			size = len(bp.Marshal())
+		}
+		b.ReportMetric(float64(size), "bytes/proof")
+	})
+	b.Run("receipts", func(b *testing.B) {
+		var size int
+		for i := 0; i < b.N; i++ {
+			size = 0
+			for _, e := range entries {
+				mdr := new(MDReceipt)
+				mdr.BuildMDReceipt(*md, e)
+				if !mdr.Validate() {
+					b.Fatal("receipt failed")
+				}
+				size += len(mdr.Marshal())
+			}
+		}
+		b.ReportMetric(float64(size), "bytes/proof")
+	})
+}
+
+func TestSubtreeRoot(t *testing.T) {
+	hashes := getTestHashes(40)
+	for start := 1; start < 20; start++ {
+		full := new(MD)
+		for _, h := range hashes[:start] {
+			full.AddToChain(h)
+		}
+		md, err := NewMD(full.Count(), full.CompressState())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, h := range hashes[start:] {
+			full.AddToChain(h)
+			md.AddToChain(h)
+		}
+
+		// A restored MD computes a range from the hashes it has, the subtrees in its state, or the two, and
+		// refuses any range it can't, rather than returning the root of another range
+		for lo := uint64(0); lo < full.Count(); lo++ {
+			for hi := lo + 1; hi <= full.Count(); hi++ {
+				want, err := full.subtreeRoot(lo, hi)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got, err := md.subtreeRoot(lo, hi)
+				if err == nil && *got != *want {
+					t.Fatalf("start %d: the root of [%d, %d) is wrong", start, lo, hi)
+				}
+				if err != nil && (lo >= uint64(start) || (lo == 0 && hi >= uint64(start))) {
+					t.Errorf("start %d: the root of [%d, %d) should be computed from the state and the hashes", start, lo, hi)
+				}
+			}
+		}
+	}
+}
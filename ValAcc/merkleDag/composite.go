@@ -0,0 +1,45 @@
+package merkleDag
+
+import (
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
+)
+
+// ReceiptLevel
+// One step of a CompositeReceipt.  The Receipt proves a hash against the ListMDRoot of a node.  The node's
+// MDRoot is then its HeaderHash combined with that ListMDRoot (see node.Node.GetMDRoot), and that MDRoot is
+// the hash proven by the next level up.
+type ReceiptLevel struct {
+	Receipt    MDReceipt  // Proves the hash from the level below against the node's ListMDRoot
+	HeaderHash types.Hash // Hash of the node; combined on the left with the ListMDRoot to give the node's MDRoot
+}
+
+// CompositeReceipt
+// Proves an entry hash all the way up to the MDRoot of a directory block.  The first level proves the entry
+// against the ListMDRoot of its chain node.  Each level after that proves the MDRoot of the node below
+// against the ListMDRoot of the node above (intermediate nodes, if any, and then the directory block).  The
+// MDRoot of the directory block is the root published by the accumulator for the block.
+type CompositeReceipt struct {
+	EntryHash types.Hash      // Entry Hash of the data subject to the receipt
+	Levels    []*ReceiptLevel // Path from the chain node up to the directory block
+	MDRoot    types.Hash      // MDRoot of the directory block
+}
+
+// Validate
+// Check every level of the receipt, and that each level proves the MDRoot of the level below, starting
+// with the entry hash and ending with the MDRoot of the directory block.
+func (cr *CompositeReceipt) Validate() bool {
+	if len(cr.Levels) == 0 {
+		return false
+	}
+	hash := cr.EntryHash
+	for _, level := range cr.Levels {
+		if level.Receipt.EntryHash != hash { // Each level must prove the hash from the level below
+			return false
+		}
+		if !level.Receipt.Validate() {
+			return false
+		}
+		hash = *level.HeaderHash.Combine(level.Receipt.MDRoot) // The MDRoot of this level's node
+	}
+	return hash == cr.MDRoot
+}
@@ -0,0 +1,56 @@
+package merkleDag
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
+)
+
+func TestCompositeReceipt(t *testing.T) {
+	// Two levels of MDs, where the root of the lower MD (with a header) is added to the upper MD
+	lower := new(MD)
+	for _, h := range getTestHashes(7) {
+		lower.AddToChain(h)
+	}
+	header := types.Hash(sha256.Sum256([]byte("header of the lower node")))
+	lowerRoot := *header.Combine(*lower.GetMDRoot())
+
+	upper := new(MD)
+	for i, h := range getTestHashes(5) {
+		if i == 3 {
+			upper.AddToChain(lowerRoot)
+		}
+		upper.AddToChain(h)
+	}
+	upperHeader := types.Hash(sha256.Sum256([]byte("header of the upper node")))
+
+	cr := new(CompositeReceipt)
+	cr.EntryHash = lower.HashList[2]
+	level := new(ReceiptLevel)
+	level.Receipt.BuildMDReceipt(*lower, cr.EntryHash)
+	level.HeaderHash = header
+	cr.Levels = append(cr.Levels, level)
+	level = new(ReceiptLevel)
+	level.Receipt.BuildMDReceipt(*upper, lowerRoot)
+	level.HeaderHash = upperHeader
+	cr.Levels = append(cr.Levels, level)
+	cr.MDRoot = *upperHeader.Combine(*upper.GetMDRoot())
+
+	if !cr.Validate() {
+		t.Fatal("the composite receipt should validate")
+	}
+	cr.Levels[0].HeaderHash[0]++ // The lower level no longer proves the hash the upper level starts with
+	if cr.Validate() {
+		t.Error("a composite receipt with a broken link should not validate")
+	}
+	cr.Levels[0].HeaderHash[0]--
+	cr.MDRoot[0]++
+	if cr.Validate() {
+		t.Error("a composite receipt with the wrong MDRoot should not validate")
+	}
+	cr.Levels = nil
+	if cr.Validate() {
+		t.Error("an empty composite receipt should not validate")
+	}
+}
@@ -0,0 +1,134 @@
+package merkleDag
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
+)
+
+// ConsistencyProof
+// Proves that the MD after Second hashes extends the MD after First hashes, i.e. that the First hashes are
+// a prefix of the Second hashes, and nothing added before was rewritten.  This is the consistency proof of
+// Certificate Transparency (RFC 9162), as the MD has the same shape as a CT Merkle tree.
+//
+// A client holding the MDRoot of a chain at First checks that it matches FirstRoot, and then Validate
+// proves the SecondRoot follows from it.
+type ConsistencyProof struct {
+	First      uint64       // Count of hashes in the older MD
+	Second     uint64       // Count of hashes in the newer MD
+	FirstRoot  types.Hash   // MDRoot after First hashes
+	SecondRoot types.Hash   // MDRoot after Second hashes
+	Hashes     []types.Hash // Roots of the subtrees needed to compute both MDRoots
+}
+
+// ConsistencyProof
+// Build a proof that the MD after second hashes extends the MD after first hashes.  Requires
+// 0 < first <= second <= Count().  If the MD was restored from an earlier state, only those proofs that
+// need no hashes from before the state can be built.
+func (m *MD) ConsistencyProof(first, second uint64) (*ConsistencyProof, error) {
+	if first == 0 || first > second || second > m.Count() {
+		return nil, errors.New(fmt.Sprintf("can't prove %d hashes consistent with %d hashes of an MD holding %d",
+			first, second, m.Count()))
+	}
+	cp := new(ConsistencyProof)
+	cp.First = first
+	cp.Second = second
+
+	// Following RFC 9162 section 2.1.4.1, prove the hashes [lo, hi) are consistent with the first hashes of the MD.
+	// complete is true while [lo, hi) holds the whole of the first MD at the start of the range
+	var subProof func(first, lo, hi uint64, complete bool) error
+	subProof = func(first, lo, hi uint64, complete bool) error {
+		if first == hi-lo {
+			if complete { // The verifier has the root of this subtree already
+				return nil
+			}
+			h, err := m.subtreeRoot(lo, hi)
+			if err != nil {
+				return err
+			}
+			cp.Hashes = append(cp.Hashes, *h)
+			return nil
+		}
+		k := splitSize(hi - lo)
+		var err error
+		var h *types.Hash
+		if first <= k { // The first hashes are all on the left, so the right subtree is all new
+			if err = subProof(first, lo, lo+k, complete); err == nil {
+				h, err = m.subtreeRoot(lo+k, hi)
+			}
+		} else { // The left subtree is all in the first hashes, the rest of them are on the right
+			if err = subProof(first-k, lo+k, hi, false); err == nil {
+				h, err = m.subtreeRoot(lo, lo+k)
+			}
+		}
+		if err != nil {
+			return err
+		}
+		cp.Hashes = append(cp.Hashes, *h)
+		return nil
+	}
+
+	if first < second {
+		if err := subProof(first, 0, second, true); err != nil {
+			return nil, err
+		}
+	}
+	firstRoot, err := m.subtreeRoot(0, first)
+	if err != nil {
+		return nil, err
+	}
+	secondRoot, err := m.subtreeRoot(0, second)
+	if err != nil {
+		return nil, err
+	}
+	cp.FirstRoot = *firstRoot
+	cp.SecondRoot = *secondRoot
+	return cp, nil
+}
+
+// Validate
+// Check that the Hashes prove the SecondRoot extends the FirstRoot.  This is the verification of
+// RFC 9162 section 2.1.4.2, using the same combining of hashes as the MD.
+func (cp *ConsistencyProof) Validate() bool {
+	if cp.First == 0 || cp.First > cp.Second {
+		return false
+	}
+	if cp.First == cp.Second {
+		return len(cp.Hashes) == 0 && cp.FirstRoot == cp.SecondRoot
+	}
+	proof := cp.Hashes
+	if cp.First&(cp.First-1) == 0 { // The first MD is a complete subtree, and the proof leaves it out
+		proof = append([]types.Hash{cp.FirstRoot}, proof...)
+	}
+	if len(proof) == 0 {
+		return false
+	}
+
+	fn, sn := cp.First-1, cp.Second-1
+	for fn&1 == 1 { // Skip the levels where the first MD is on the right edge of a complete subtree
+		fn >>= 1
+		sn >>= 1
+	}
+	fr, sr := proof[0], proof[0]
+	for _, c := range proof[1:] {
+		if sn == 0 {
+			return false
+		}
+		if fn&1 == 1 || fn == sn { // c is on the left of both roots
+			fr = *c.Combine(fr)
+			sr = *c.Combine(sr)
+			if fn&1 == 0 {
+				for fn&1 == 0 && fn != 0 {
+					fn >>= 1
+					sn >>= 1
+				}
+			}
+		} else { // c is on the right, and only part of the second root
+			sr = *sr.Combine(c)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	return sn == 0 && fr == cp.FirstRoot && sr == cp.SecondRoot
+}
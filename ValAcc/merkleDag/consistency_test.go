@@ -0,0 +1,91 @@
+package merkleDag
+
+import (
+	"testing"
+)
+
+func TestConsistencyProof(t *testing.T) {
+	hashes := getTestHashes(40)
+	md := new(MD)
+	var roots []*MD // roots[n] is the MD after n hashes
+	roots = append(roots, nil)
+	for _, h := range hashes {
+		md.AddToChain(h)
+		prefix := new(MD)
+		for _, p := range md.HashList {
+			prefix.AddToChain(p)
+		}
+		roots = append(roots, prefix)
+	}
+
+	for first := uint64(1); first <= 40; first++ {
+		for second := first; second <= 40; second++ {
+			cp, err := md.ConsistencyProof(first, second)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cp.FirstRoot != *roots[first].GetMDRoot() || cp.SecondRoot != *roots[second].GetMDRoot() {
+				t.Fatalf("%d to %d: the roots in the proof should be the MDRoots", first, second)
+			}
+			if !cp.Validate() {
+				t.Fatalf("%d to %d: the proof should validate", first, second)
+			}
+
+			for i := range cp.Hashes { // Any change to any hash fails
+				cp.Hashes[i][0]++
+				if cp.Validate() {
+					t.Fatalf("%d to %d: a proof with a bad hash %d should not validate", first, second, i)
+				}
+				cp.Hashes[i][0]--
+			}
+			cp.FirstRoot[0]++
+			if cp.Validate() {
+				t.Fatalf("%d to %d: a proof with the wrong first root should not validate", first, second)
+			}
+			cp.FirstRoot[0]--
+			cp.SecondRoot[0]++
+			if cp.Validate() {
+				t.Fatalf("%d to %d: a proof with the wrong second root should not validate", first, second)
+			}
+		}
+	}
+
+	// A history that was rewritten can't be proven consistent
+	rewritten := new(MD)
+	for i, h := range hashes {
+		if i == 3 {
+			h[0]++
+		}
+		rewritten.AddToChain(h)
+	}
+	cp, _ := rewritten.ConsistencyProof(10, 30)
+	cp.FirstRoot = *roots[10].GetMDRoot()
+	if cp.Validate() {
+		t.Error("a rewritten MD should not prove consistent with the original root")
+	}
+
+	if _, err := md.ConsistencyProof(0, 5); err == nil {
+		t.Error("there is no proof from an empty MD")
+	}
+	if _, err := md.ConsistencyProof(10, 41); err == nil {
+		t.Error("there is no proof past the end of the MD")
+	}
+
+	// An MD restored from a state can prove consistency from the state on
+	restored, err := NewMD(roots[13].Count(), roots[13].CompressState())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, h := range hashes[13:] {
+		restored.AddToChain(h)
+	}
+	for first := uint64(13); first <= 40; first++ {
+		cp, err := restored.ConsistencyProof(first, 40)
+		if err != nil {
+			t.Fatalf("%d to 40: %v", first, err)
+		}
+		if !cp.Validate() || cp.FirstRoot != *roots[first].GetMDRoot() {
+			t.Errorf("%d to 40: the proof from the restored MD should validate", first)
+		}
+	}
+}
@@ -1,14 +1,84 @@
 package merkleDag
 
 import (
+	"errors"
+	"fmt"
+
 	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
 )
 
 // MD
 // Collects Hashes from some source, and allows the creation of MD Roots as desired.
+//
+// An MD can be restored from the state of an earlier MD (see NewMD), so a chain's MD can run across many
+// blocks.  In that case HashList only holds the hashes added since the state was restored.
 type MD struct {
-	MD       []*types.Hash // Array of hashes that represent the right edge of the Merkle tree
-	HashList []types.Hash  // List of Hashes in the order added to the chain
+	MD         []*types.Hash // Array of hashes that represent the right edge of the Merkle tree
+	HashList   []types.Hash  // List of Hashes in the order added to the chain
+	StartCount uint64        // Count of hashes added to the MD before those in HashList
+	StartState []*types.Hash // The right edge of the Merkle tree before the hashes in HashList were added
+}
+
+// NewMD
+// Restore an MD from the count of hashes added to it and its compressed state (see CompressState).
+// Returns an error if the state does not match the count.
+func NewMD(count uint64, state []types.Hash) (*MD, error) {
+	md := new(MD)
+	expanded, err := ExpandState(count, state)
+	if err != nil {
+		return nil, err
+	}
+	md.MD = expanded
+	md.StartCount = count
+	md.StartState = append([]*types.Hash{}, expanded...)
+	return md, nil
+}
+
+// Count
+// Returns the count of all the hashes added to the MD, including those added before its state was restored
+func (m *MD) Count() uint64 {
+	return m.StartCount + uint64(len(m.HashList))
+}
+
+// CompressState
+// Returns the hashes in the right edge of the Merkle tree without the nil entries.  Because a hash is at
+// MD[i] if and only if bit i of the count of hashes added is set, the count and the compressed
+// state are enough to restore the MD.
+func (m *MD) CompressState() (state []types.Hash) {
+	for _, v := range m.MD {
+		if v != nil {
+			state = append(state, *v)
+		}
+	}
+	return state
+}
+
+// startEdge
+// Returns a copy of the right edge of the Merkle tree before the hashes in HashList were added, ending
+// in a nil, ready to add the HashList again.
+func (m *MD) startEdge() []*types.Hash {
+	md := append([]*types.Hash{}, m.StartState...)
+	return append(md, nil)
+}
+
+// ExpandState
+// Rebuild the right edge of a Merkle tree from the count of hashes added and the compressed state.
+func ExpandState(count uint64, state []types.Hash) (md []*types.Hash, err error) {
+	for i := 0; count>>uint(i) > 0; i++ {
+		if count>>uint(i)&1 == 0 {
+			md = append(md, nil)
+			continue
+		}
+		if len(state) == 0 {
+			return nil, errors.New(fmt.Sprintf("state has too few hashes for a count of %d", count))
+		}
+		md = append(md, state[0].Copy())
+		state = state[1:]
+	}
+	if len(state) != 0 {
+		return nil, errors.New(fmt.Sprintf("state has %d hashes too many for a count of %d", len(state), count))
+	}
+	return md, nil
 }
 
 // GetHashList
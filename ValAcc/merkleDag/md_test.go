@@ -0,0 +1,62 @@
+package merkleDag
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"testing"
+
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
+)
+
+// getTestHashes
+// Returns a list of cnt hashes for use in tests
+func getTestHashes(cnt int) (hashes []types.Hash) {
+	for i := 0; i < cnt; i++ {
+		hashes = append(hashes, sha256.Sum256([]byte(fmt.Sprint("hash ", i))))
+	}
+	return hashes
+}
+
+func TestMDState(t *testing.T) {
+	hashes := getTestHashes(300)
+	full := new(MD)
+	for _, h := range hashes {
+		full.AddToChain(h)
+	}
+
+	// Restoring the state of an MD at any point, then adding the rest of the hashes, should give the
+	// same MDRoot as adding all the hashes to one MD.  And receipts should still validate.
+	for split := 0; split < len(hashes); split += 7 {
+		start := new(MD)
+		for _, h := range hashes[:split] {
+			start.AddToChain(h)
+		}
+		md, err := NewMD(start.Count(), start.CompressState())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, h := range hashes[split:] {
+			md.AddToChain(h)
+		}
+		if md.Count() != uint64(len(hashes)) || len(md.HashList) != len(hashes)-split {
+			t.Errorf("split %d: count %d and hash list of %d not as expected", split, md.Count(), len(md.HashList))
+		}
+		if *md.GetMDRoot() != *full.GetMDRoot() {
+			t.Errorf("split %d: MDRoot of the restored MD doesn't match", split)
+		}
+		for _, h := range hashes[split:] {
+			receipt := new(MDReceipt)
+			receipt.BuildMDReceipt(*md, h)
+			if receipt.MDRoot != *full.GetMDRoot() || !receipt.Validate() {
+				t.Errorf("split %d: receipt for %x fails to validate", split, h[:4])
+			}
+		}
+	}
+
+	if _, err := NewMD(5, getTestHashes(3)); err == nil {
+		t.Error("a count of 5 needs a state of 2 hashes")
+	}
+	if _, err := NewMD(7, getTestHashes(2)); err == nil {
+		t.Error("a count of 7 needs a state of 3 hashes")
+	}
+}
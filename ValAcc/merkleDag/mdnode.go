@@ -1,7 +1,17 @@
 package merkleDag
 
-import "github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
 
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
+)
+
+// MDNode
+// The stored form of one block of a chain's MD.  It holds the state of the chain's MD at the start of the
+// block, and the hashes added to the chain in the block.  From that the MD at the end of the block can be
+// rebuilt (see GetMD), and the next block of the chain can continue the MD where this one left off.
 type MDNode struct {
 	Type           uint8        // Type of data in this chain, drives validation
 	SequenceNumber uint32       // sequence number of MDNodes in this chain
@@ -12,6 +22,53 @@ type MDNode struct {
 	Hashes         []types.Hash // Hashes in this block
 }
 
+// NewMDNode
+// Build the MDNode for the block of hashes in the given MD.  The state at the start of the block is the
+// state the MD was restored from (if any), and the hashes in the block are the MD's HashList.  The caller
+// sets the Type, SequenceNumber and Previous.
+func NewMDNode(md *MD) *MDNode {
+	n := new(MDNode)
+	start := new(MD)
+	start.MD = md.StartState
+	n.TotalEntries = md.StartCount
+	n.MDSTate = start.CompressState()
+	if root := start.GetMDRoot(); root != nil {
+		n.MDRoot = *root
+	}
+	n.Hashes = append(n.Hashes, md.HashList...)
+	return n
+}
+
+// GetMD
+// Rebuild the MD at the end of this block.  The MD is restored from the state at the start of the block,
+// then the hashes in this block are added, so the HashList of the MD holds just this block's hashes.
+// Returns an error if the state at the start of the block doesn't match the TotalEntries or the MDRoot.
+func (n *MDNode) GetMD() (*MD, error) {
+	md, err := NewMD(n.TotalEntries, n.MDSTate)
+	if err != nil {
+		return nil, err
+	}
+	root := md.GetMDRoot()
+	if root == nil {
+		root = new(types.Hash)
+	}
+	if *root != n.MDRoot {
+		return nil, errors.New(fmt.Sprintf("MDRoot %x does not match the MD state, which has the MDRoot %x",
+			n.MDRoot, *root))
+	}
+	for _, h := range n.Hashes {
+		md.AddToChain(h)
+	}
+	return md, nil
+}
+
+// GetHash
+// Returns the hash of the MDNode
+func (n *MDNode) GetHash() *types.Hash {
+	h := types.Hash(sha256.Sum256(n.Bytes()))
+	return &h
+}
+
 // SameAs
 // Compare all the data in two nodes to determine that they are the same.  Any difference, and they are false
 func (n *MDNode) SameAs(n2 *MDNode) bool {
@@ -27,6 +84,14 @@ func (n *MDNode) SameAs(n2 *MDNode) bool {
 	if n.TotalEntries != n2.TotalEntries {
 		return false
 	}
+	if len(n.MDSTate) != len(n2.MDSTate) { // Check the state
+		return false
+	}
+	for i, h := range n.MDSTate {
+		if h != n2.MDSTate[i] {
+			return false
+		}
+	}
 	if n.MDRoot != n2.MDRoot { // Check the MDRoot
 		return false
 	}
@@ -44,6 +109,12 @@ func (n *MDNode) SameAs(n2 *MDNode) bool {
 func (n *MDNode) Bytes() (data []byte) {
 	data = append(data, byte(n.Type))
 	data = append(data, types.Uint32Bytes(n.SequenceNumber)...)
+	data = append(data, n.Previous.Bytes()...)
+	data = append(data, types.Uint64Bytes(n.TotalEntries)...)
+	data = append(data, types.Uint16Bytes(uint16(len(n.MDSTate)))...) // At most 64 hashes in the state
+	for _, h := range n.MDSTate {
+		data = append(data, h.Bytes()...)
+	}
 	data = append(data, n.MDRoot.Bytes()...)
 	data = append(data, types.Uint32Bytes(uint32(len(n.Hashes)))...)
 	for _, h := range n.Hashes {
@@ -52,10 +123,20 @@ func (n *MDNode) Bytes() (data []byte) {
 	return data
 }
 
-func (n *MDNode) Extract(data []byte) {
-	n.Hashes = n.Hashes[:0] // Clear any old hashes
+func (n *MDNode) Extract(data []byte) []byte {
+	n.MDSTate = n.MDSTate[:0] // Clear any old state
+	n.Hashes = n.Hashes[:0]   // Clear any old hashes
 	n.Type, data = data[0], data[1:]
 	n.SequenceNumber, data = types.BytesUint32(data)
+	data = n.Previous.Extract(data)
+	n.TotalEntries, data = types.BytesUint64(data)
+	var stateLen uint16
+	stateLen, data = types.BytesUint16(data)
+	for i := uint16(0); i < stateLen; i++ {
+		var h types.Hash
+		data = h.Extract(data)
+		n.MDSTate = append(n.MDSTate, h)
+	}
 	data = n.MDRoot.Extract(data)
 	var numHashes, i uint32
 	numHashes, data = types.BytesUint32(data)
@@ -64,11 +145,17 @@ func (n *MDNode) Extract(data []byte) {
 		data = h.Extract(data)
 		n.Hashes = append(n.Hashes, h)
 	}
+	return data
 }
 
-// CompressState
-// First check to make sure the current state matches the total number of entries at the start of this
-// block.
-func (n *MDNode) CompressState() (state []types.Hash) {
-	return
+// Unmarshal
+// Extract an MDNode from a byte slice.  Returns an error if the unmarshal fails, or the length of the
+// data consumed and a nil.
+func (n *MDNode) Unmarshal(data []byte) (dataConsumed int, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New(fmt.Sprintf("MDNode Failed to unmarshal %v", r))
+		}
+	}()
+	return len(data) - len(n.Extract(data)), nil
 }
@@ -0,0 +1,53 @@
+package merkleDag
+
+import (
+	"testing"
+)
+
+func TestMDNode(t *testing.T) {
+	hashes := getTestHashes(100)
+	start := new(MD)
+	for _, h := range hashes[:37] {
+		start.AddToChain(h)
+	}
+	md, _ := NewMD(start.Count(), start.CompressState())
+	for _, h := range hashes[37:] {
+		md.AddToChain(h)
+	}
+
+	n := NewMDNode(md)
+	n.SequenceNumber = 3
+	n.Previous = hashes[0]
+	if n.TotalEntries != 37 || n.MDRoot != *start.GetMDRoot() || len(n.Hashes) != 63 {
+		t.Error("the MDNode should hold the state at the start of the block and the hashes of the block")
+	}
+
+	data := n.Bytes()
+	n2 := new(MDNode)
+	consumed, err := n2.Unmarshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if consumed != len(data) || !n.SameAs(n2) {
+		t.Error("the MDNode did not unmarshal as marshaled")
+	}
+	if *n.GetHash() != *n2.GetHash() {
+		t.Error("the same MDNode should have the same hash")
+	}
+
+	md2, err := n2.GetMD()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *md2.GetMDRoot() != *md.GetMDRoot() || md2.Count() != 100 || len(md2.HashList) != 63 {
+		t.Error("the MD rebuilt from the MDNode should match the MD at the end of the block")
+	}
+
+	n2.MDRoot[0] ^= 1
+	if _, err := n2.GetMD(); err == nil {
+		t.Error("an MDNode with an MDRoot that doesn't match its state should not give an MD")
+	}
+	if _, err := n2.Unmarshal(data[:len(data)-1]); err == nil {
+		t.Error("a truncated MDNode should fail to unmarshal")
+	}
+}
@@ -24,11 +24,11 @@ type MDReceipt struct {
 // Then when we have to calculate the Merkle DAG root, we do one more pass through the combining of the
 // trailing hashes to finish off the receipt.
 func (mdr *MDReceipt) BuildMDReceipt(MerkleDag MD, data types.Hash) {
-	mdr.Nodes = mdr.Nodes[:0] // Throw away any old paths
-	mdr.EntryHash = data      // The Data for which this is a Receipt
-	md := []*types.Hash{nil}  // The intermediate hashes used to compute the Merkle DAG root
-	right := true             // We assume we will be combining from the right
-	idx := -1                 // idx of -1 means not yet found the hash for which we want a receipt in the hash stream
+	mdr.Nodes = mdr.Nodes[:0]   // Throw away any old paths
+	mdr.EntryHash = data        // The Data for which this is a Receipt
+	md := MerkleDag.startEdge() // The intermediate hashes used to compute the Merkle DAG root
+	right := true               // We assume we will be combining from the right
+	idx := -1                   // idx of -1 means not yet found the hash for which we want a receipt in the hash stream
 
 DataLoop: // Loop through the data behind the Merkle DAG and rebuild the MD state
 	for _, h := range MerkleDag.HashList {
@@ -102,41 +102,33 @@ DataLoop: // Loop through the data behind the Merkle DAG and rebuild the MD stat
 		return
 	}
 	var mdRoot *types.Hash // mdRoot is the merkle DAG root we are building.
-	right = true
+	inRoot := false        // Set once our hash has been folded into the mdRoot
 
 	// We close the Merkle DAG
 	for i, v := range md {
-		if mdRoot == nil { // If we have not found a hash yet, try and pick one up here.
-			if i == idx {
-				if v == nil { // This should never happen.
-					panic("found our hash level, but no hash was found.")
-				}
-				right = false // Now our hash is on the right, will be combined with a hash on the left, or none at all.
-				idx++
-			}
-			if v != nil {
-				mdRoot = new(types.Hash)
-				copy(mdRoot[:], v[:]) // Pick up this hash, and look for the next
-			}
+		if v == nil { // Nothing to combine at this level
 			continue
 		}
-		if v != nil { // If MDRoot isn't nil and v isn't nil, we combine them.
-			if i == idx {
-				rn := new(ReceiptNode)
-				mdr.Nodes = append(mdr.Nodes, rn)
-				rn.Right = right
-				if right {
-					copy(rn.Hash[:], mdRoot[:])
-				} else {
-					copy(rn.Hash[:], v[:])
-				}
-				right = false
-				idx++
-			}
-			mdRoot = v.Combine(*mdRoot)    // v is on the left, MDRoot candidate is on the right, for a new MDRoot
-			copy(mdr.MDRoot[:], mdRoot[:]) // The last one is the one we want
+		if mdRoot == nil { // Pick up the first hash we find
+			mdRoot = v.Copy()
+			inRoot = i == idx // If this is our hash, then everything from here on combines with it
+			continue
+		}
+		if inRoot { // Our hash is in the mdRoot, so it is on the right, and v is on the left
+			rn := new(ReceiptNode)
+			mdr.Nodes = append(mdr.Nodes, rn)
+			rn.Right = false
+			rn.Hash = *v.Copy()
+		} else if i == idx { // Our hash is v, on the left, so the mdRoot is on the right
+			rn := new(ReceiptNode)
+			mdr.Nodes = append(mdr.Nodes, rn)
+			rn.Right = true
+			rn.Hash = *mdRoot.Copy()
+			inRoot = true
 		}
+		mdRoot = v.Combine(*mdRoot) // v is on the left, MDRoot candidate is on the right, for a new MDRoot
 	}
+	mdr.MDRoot = *mdRoot // The last one is the one we want
 	return
 }
 
@@ -0,0 +1,156 @@
+package merkleDag
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
+)
+
+// MDReceiptVersion
+// Version of the binary and JSON encodings of an MDReceipt.  Bump this on any change to either encoding.
+const MDReceiptVersion = 1
+
+// Marshal
+// The compact binary encoding of an MDReceipt.  All hashes are 32 bytes, and the count is a types varint.
+//
+//	version      byte      MDReceiptVersion
+//	EntryHash    [32]byte
+//	len(Nodes)   varint
+//	  Right      byte      1 if the Hash is combined on the right, 0 if on the left
+//	  Hash       [32]byte
+//	MDRoot       [32]byte
+func (mdr *MDReceipt) Marshal() (data []byte) {
+	data = append(data, MDReceiptVersion)
+	data = append(data, mdr.EntryHash.Bytes()...)
+	data = append(data, types.EncodeVarIntGoBytes(uint64(len(mdr.Nodes)))...)
+	for _, n := range mdr.Nodes {
+		data = append(data, types.BoolBytes(n.Right)...)
+		data = append(data, n.Hash.Bytes()...)
+	}
+	data = append(data, mdr.MDRoot.Bytes()...)
+	return data
+}
+
+// Unmarshal
+// Extract an MDReceipt from its binary encoding.  Returns an error if the unmarshal fails, or the length of
+// the data consumed and a nil.
+func (mdr *MDReceipt) Unmarshal(data []byte) (dataConsumed int, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New(fmt.Sprintf("MDReceipt Failed to unmarshal %v", r))
+		}
+	}()
+	d := data
+	if data[0] != MDReceiptVersion {
+		return 0, errors.New(fmt.Sprintf("MDReceipt version %d is not supported", data[0]))
+	}
+	data = data[1:]
+	data = mdr.EntryHash.Extract(data)
+	cnt, rest := types.DecodeVarInt(data)
+	if len(rest) == 0 || data[len(data)-len(rest)-1] >= 0x80 { // The varint must end before the data does
+		return 0, errors.New("MDReceipt has a truncated node count")
+	}
+	data = rest
+	if cnt > uint64(len(data)/33) { // Don't allocate more nodes than the data could possibly hold
+		return 0, errors.New(fmt.Sprintf("MDReceipt claims %d nodes, more than the data holds", cnt))
+	}
+	mdr.Nodes = make([]*ReceiptNode, 0, cnt)
+	for i := uint64(0); i < cnt; i++ {
+		n := new(ReceiptNode)
+		if data[0] > 1 {
+			return 0, errors.New(fmt.Sprintf("MDReceipt node %d has an invalid side %d", i, data[0]))
+		}
+		n.Right, data = types.BytesBool(data)
+		data = n.Hash.Extract(data)
+		mdr.Nodes = append(mdr.Nodes, n)
+	}
+	data = mdr.MDRoot.Extract(data)
+	return len(d) - len(data), nil
+}
+
+// receiptJSON
+// The canonical JSON form of an MDReceipt.  Hashes are lower case hex, and each node says explicitly which
+// side its hash is combined on.  To verify, start with the entryHash and for each node compute
+// sha256(running || hash) if the side is "right", or sha256(hash || running) if "left".  The result must
+// be the mdRoot.
+type receiptJSON struct {
+	Version   int               `json:"version"`
+	EntryHash string            `json:"entryHash"`
+	Nodes     []receiptNodeJSON `json:"nodes"`
+	MDRoot    string            `json:"mdRoot"`
+}
+
+type receiptNodeJSON struct {
+	Side string `json:"side"` // "left" or "right"; the side on which Hash is combined with the running hash
+	Hash string `json:"hash"`
+}
+
+// MarshalJSON
+// Encode the MDReceipt in its canonical JSON form
+func (mdr MDReceipt) MarshalJSON() ([]byte, error) {
+	rj := receiptJSON{Version: MDReceiptVersion, Nodes: []receiptNodeJSON{}}
+	rj.EntryHash = hex.EncodeToString(mdr.EntryHash[:])
+	for _, n := range mdr.Nodes {
+		side := "left"
+		if n.Right {
+			side = "right"
+		}
+		rj.Nodes = append(rj.Nodes, receiptNodeJSON{Side: side, Hash: hex.EncodeToString(n.Hash[:])})
+	}
+	rj.MDRoot = hex.EncodeToString(mdr.MDRoot[:])
+	return json.Marshal(rj)
+}
+
+// UnmarshalJSON
+// Decode an MDReceipt from its canonical JSON form
+func (mdr *MDReceipt) UnmarshalJSON(data []byte) error {
+	var rj receiptJSON
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&rj); err != nil {
+		return err
+	}
+	if rj.Version != MDReceiptVersion {
+		return errors.New(fmt.Sprintf("MDReceipt version %d is not supported", rj.Version))
+	}
+	if err := hexHash(rj.EntryHash, &mdr.EntryHash); err != nil {
+		return errors.New(fmt.Sprintf("bad entryHash: %v", err))
+	}
+	mdr.Nodes = mdr.Nodes[:0]
+	for i, nj := range rj.Nodes {
+		n := new(ReceiptNode)
+		switch nj.Side {
+		case "right":
+			n.Right = true
+		case "left":
+		default:
+			return errors.New(fmt.Sprintf("node %d has an invalid side %q", i, nj.Side))
+		}
+		if err := hexHash(nj.Hash, &n.Hash); err != nil {
+			return errors.New(fmt.Sprintf("bad hash in node %d: %v", i, err))
+		}
+		mdr.Nodes = append(mdr.Nodes, n)
+	}
+	if err := hexHash(rj.MDRoot, &mdr.MDRoot); err != nil {
+		return errors.New(fmt.Sprintf("bad mdRoot: %v", err))
+	}
+	return nil
+}
+
+// hexHash
+// Decode a hex string holding exactly one hash
+func hexHash(s string, h *types.Hash) error {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	if len(b) != len(h) {
+		return errors.New(fmt.Sprintf("expected %d bytes, got %d", len(h), len(b)))
+	}
+	h.Extract(b)
+	return nil
+}
@@ -0,0 +1,130 @@
+package merkleDag
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden receipt vectors in testdata")
+
+// getGoldenReceipts
+// The receipts behind the golden vectors.  Changing these changes the vectors external verifiers test against.
+func getGoldenReceipts() (receipts []*MDReceipt) {
+	hashes := getTestHashes(7)
+	md := new(MD)
+	for _, h := range hashes {
+		md.AddToChain(h)
+	}
+	for _, i := range []int{0, 2, 6} {
+		mdr := new(MDReceipt)
+		mdr.BuildMDReceipt(*md, hashes[i])
+		receipts = append(receipts, mdr)
+	}
+	single := new(MD) // A receipt with no nodes at all
+	single.AddToChain(hashes[0])
+	mdr := new(MDReceipt)
+	mdr.BuildMDReceipt(*single, hashes[0])
+	return append(receipts, mdr)
+}
+
+// checkGolden
+// Compare data against the golden file, or rewrite the golden file if -update is given
+func checkGolden(t *testing.T, name string, data []byte) {
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := ioutil.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	golden, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(golden, data) {
+		t.Errorf("%s does not match the golden vector.\nexpected:\n%s\ngot:\n%s", path, golden, data)
+	}
+}
+
+func TestReceiptBinary(t *testing.T) {
+	var vectors []string
+	for i, mdr := range getGoldenReceipts() {
+		if !mdr.Validate() {
+			t.Fatalf("golden receipt %d doesn't validate", i)
+		}
+		data := mdr.Marshal()
+		vectors = append(vectors, hex.EncodeToString(data))
+
+		mdr2 := new(MDReceipt)
+		consumed, err := mdr2.Unmarshal(append(data, 0xFF)) // Trailing data is left alone
+		if err != nil {
+			t.Fatal(err)
+		}
+		if consumed != len(data) {
+			t.Errorf("expected to consume %d bytes, consumed %d", len(data), consumed)
+		}
+		if !bytes.Equal(mdr2.Marshal(), data) || !mdr2.Validate() {
+			t.Errorf("receipt %d did not survive a round trip", i)
+		}
+
+		for cut := 0; cut < len(data); cut++ { // Every truncation fails
+			if _, err := new(MDReceipt).Unmarshal(data[:cut]); err == nil {
+				t.Fatalf("receipt %d truncated to %d bytes should not unmarshal", i, cut)
+			}
+		}
+	}
+	checkGolden(t, "mdreceipt.hex", []byte(strings.Join(vectors, "\n")+"\n"))
+
+	bad := getGoldenReceipts()[1].Marshal()
+	bad[0] = MDReceiptVersion + 1
+	if _, err := new(MDReceipt).Unmarshal(bad); err == nil {
+		t.Error("an unknown version should not unmarshal")
+	}
+	bad = getGoldenReceipts()[1].Marshal()
+	bad[34] = 2 // The side of the first node
+	if _, err := new(MDReceipt).Unmarshal(bad); err == nil {
+		t.Error("an invalid side should not unmarshal")
+	}
+}
+
+func TestReceiptJSON(t *testing.T) {
+	var vectors []json.RawMessage
+	for i, mdr := range getGoldenReceipts() {
+		data, err := json.Marshal(mdr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		vectors = append(vectors, data)
+
+		mdr2 := new(MDReceipt)
+		if err := json.Unmarshal(data, mdr2); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(mdr2.Marshal(), mdr.Marshal()) || !mdr2.Validate() {
+			t.Errorf("receipt %d did not survive a JSON round trip", i)
+		}
+	}
+	data, err := json.MarshalIndent(vectors, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "mdreceipt.json", append(data, '\n'))
+
+	for _, bad := range []string{
+		`{"version":2,"entryHash":"","nodes":[],"mdRoot":""}`,
+		`{"version":1,"entryHash":"00","nodes":[],"mdRoot":"00"}`,
+		`{"version":1,"entryHash":"` + strings.Repeat("00", 32) + `","nodes":[{"side":"up","hash":"` +
+			strings.Repeat("00", 32) + `"}],"mdRoot":"` + strings.Repeat("00", 32) + `"}`,
+		`{"version":1,"extra":true}`,
+	} {
+		if err := json.Unmarshal([]byte(bad), new(MDReceipt)); err == nil {
+			t.Errorf("%s should not unmarshal", bad)
+		}
+	}
+}
@@ -156,7 +156,7 @@ func (e *ANode) Unmarshal(data []byte) (dataConsumed int, err error) {
 	}
 
 	lContent, data := types.BytesUint16(data) // Get the length of the content
-	e.Content.Extract(lContent, data)         //Extract the content
+	data = e.Content.Extract(lContent, data)  //Extract the content
 
 	return len(d) - len(data), nil // Return the bytes consumed and a nil that all is well for an error
 
@@ -169,3 +169,42 @@ type EntryHash struct {
 	ChainID   types.Hash   // The ChainID
 	EntryHash types.Hash   // The EntryHash
 }
+
+// Marshal
+// Convert the EntryHash into a byte slice
+func (e EntryHash) Marshal() (bytes []byte) {
+	bytes = append(bytes, types.Uint16Bytes(uint16(len(e.SubChains)))...) // Put the number of SubChains
+	for _, subChain := range e.SubChains {                                // For each SubChain
+		bytes = append(bytes, subChain.Bytes()...) // Put the SubChain in the slice
+	}
+	bytes = append(bytes, e.ChainID.Bytes()...)
+	bytes = append(bytes, e.EntryHash.Bytes()...)
+	return bytes
+}
+
+// Unmarshal
+// Extract an EntryHash from a byte slice.  Returns an error if the unmarshal fails, or the length of the
+// data consumed and a nil.
+func (e *EntryHash) Unmarshal(data []byte) (dataConsumed int, err error) {
+
+	// On any error, no data is consumed and return an error as to why unmarshal fails
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New(fmt.Sprintf("EntryHash Failed to unmarshal %v", r))
+		}
+	}()
+	d := data                      // d keeps the original slice
+	e.SubChains = e.SubChains[0:0] // Clear any SubChains that might already be in this EntryHash
+
+	var numSubChains uint16
+	numSubChains, data = types.BytesUint16(data) // Get the number of SubChainIDs we should have
+	for i := uint16(0); i < numSubChains; i++ {  // Pull each of them out of the data slice
+		sc := types.Hash{}
+		data = sc.Extract(data)
+		e.SubChains = append(e.SubChains, sc)
+	}
+	data = e.ChainID.Extract(data)
+	data = e.EntryHash.Extract(data)
+
+	return len(d) - len(data), nil
+}
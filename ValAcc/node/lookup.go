@@ -0,0 +1,166 @@
+package node
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/database"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
+)
+
+// EntryLocation
+// Where an entry is recorded.  The node holding the entry, the block height of that node, and the
+// position of the entry in the node's EntryList
+type EntryLocation struct {
+	NodeHash types.Hash        // Hash of the node holding the entry
+	Node     *Node             // The node holding the entry
+	BHeight  types.BlockHeight // Block Height of the node holding the entry
+	Position int               // Index of the entry in Node.EntryList
+}
+
+// GetNode
+// Get the node with the given hash from the database.  Returns an error if the node is not found
+// or fails to unmarshal.
+func GetNode(db *database.DB, nodeHash []byte) (*Node, error) {
+	nodeBytes := db.Get(types.Node, nodeHash)
+	if nodeBytes == nil {
+		return nil, errors.New(fmt.Sprintf("node %x not found", nodeHash))
+	}
+	n := new(Node)
+	if _, err := n.Unmarshal(nodeBytes); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// GetEntryLocation
+// Look up the node where the given entry hash is recorded.  Returns an error if the entry has not been
+// recorded in a sealed block.
+func GetEntryLocation(db *database.DB, entryHash types.Hash) (*EntryLocation, error) {
+	nodeHash := db.Get(types.EntryNode, entryHash.Bytes())
+	if nodeHash == nil {
+		return nil, errors.New(fmt.Sprintf("entry %x not found", entryHash))
+	}
+	n, err := GetNode(db, nodeHash)
+	if err != nil {
+		return nil, err
+	}
+	loc := new(EntryLocation)
+	loc.NodeHash.Extract(nodeHash)
+	loc.Node = n
+	loc.BHeight = n.BHeight
+	loc.Position = -1
+	for i, h := range n.EntryList {
+		if h == entryHash {
+			loc.Position = i
+			break
+		}
+	}
+	if loc.Position < 0 {
+		return nil, errors.New(fmt.Sprintf("entry %x is indexed to node %x, but not found in it", entryHash, nodeHash))
+	}
+	return loc, nil
+}
+
+// GetDirectoryBlock
+// Get the directory block at the given block height.  Returns an error if there is no directory block
+// at that height.
+func GetDirectoryBlock(db *database.DB, height types.BlockHeight) (*Node, error) {
+	nodeHash := db.GetInt32(types.DirectoryBlockHeight, uint32(height))
+	if nodeHash == nil {
+		return nil, errors.New(fmt.Sprintf("no directory block at height %d", height))
+	}
+	return GetNode(db, nodeHash)
+}
+
+// GetNodeByMDRoot
+// Get the node with the given MDRoot, i.e. the node behind an NEList.  Returns an error if no node with
+// that MDRoot is found.
+func GetNodeByMDRoot(db *database.DB, mdRoot types.Hash) (*Node, error) {
+	nodeHash := db.Get(types.MDRootNode, mdRoot.Bytes())
+	if nodeHash == nil {
+		return nil, errors.New(fmt.Sprintf("no node found with the MDRoot %x", mdRoot))
+	}
+	return GetNode(db, nodeHash)
+}
+
+// GetNodePath
+// Walk down from the directory block at the given height to the node for the given chain.  Returns the
+// path from the chain node back up to the directory block, i.e. the chain node first, then any
+// intermediate nodes, and the directory block last.  Returns an error if the chain was not updated at
+// that height.
+func GetNodePath(db *database.DB, height types.BlockHeight, chainID types.Hash) (path []*Node, err error) {
+	n, err := GetDirectoryBlock(db, height)
+	if err != nil {
+		return nil, err
+	}
+	for n.IsNode {
+		path = append([]*Node{n}, path...)
+		next := -1
+		for i, ne := range n.List { // The last range starting at or before our chain holds our chain
+			if bytes.Compare(ne.ChainID[:], chainID[:]) > 0 {
+				break
+			}
+			next = i
+		}
+		if next < 0 {
+			return nil, errors.New(fmt.Sprintf("chain %x not found at height %d", chainID, height))
+		}
+		if n, err = GetNodeByMDRoot(db, n.List[next].MDRoot); err != nil {
+			return nil, err
+		}
+	}
+	if n.ChainID != chainID {
+		return nil, errors.New(fmt.Sprintf("chain %x not found at height %d", chainID, height))
+	}
+	return append([]*Node{n}, path...), nil
+}
+
+// GetChainsAtHeight
+// Returns the chains updated at the given block height, each with the MDRoot of the chain's node at
+// that height, in ChainID order.  Any intermediate nodes between the directory block and the chain
+// nodes are walked down to the chains they cover.
+func GetChainsAtHeight(db *database.DB, height types.BlockHeight) ([]NEList, error) {
+	directoryBlock, err := GetDirectoryBlock(db, height)
+	if err != nil {
+		return nil, err
+	}
+	return expandList(db, directoryBlock.List)
+}
+
+// expandList
+// Replace every intermediate node in the list with the chains it covers
+func expandList(db *database.DB, list []NEList) (chains []NEList, err error) {
+	for _, ne := range list {
+		n, err := GetNodeByMDRoot(db, ne.MDRoot)
+		if err != nil {
+			return nil, err
+		}
+		if !n.IsNode { // A chain node
+			chains = append(chains, ne)
+			continue
+		}
+		sub, err := expandList(db, n.List)
+		if err != nil {
+			return nil, err
+		}
+		chains = append(chains, sub...)
+	}
+	return chains, nil
+}
+
+// GetEntry
+// Get the content of the entry with the given hash, as stored by the Validator.  Returns an error if the
+// entry is not found.
+func GetEntry(db *database.DB, entryHash types.Hash) (*ANode, error) {
+	data := db.Get(types.Entry, entryHash.Bytes())
+	if data == nil {
+		return nil, errors.New(fmt.Sprintf("entry %x not found", entryHash))
+	}
+	entry := new(ANode)
+	if _, err := entry.Unmarshal(data); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
@@ -0,0 +1,40 @@
+package node
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"testing"
+
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
+)
+
+func TestEntryLocation(t *testing.T) {
+	db := GetTestDB(t)
+	n := GetTestNode(t)
+	n.SequenceNum = 0 // First node in the chain
+	n.IsNode = false  // An entry node
+	n.List = nil
+	for i := 0; i < 10; i++ {
+		n.EntryList = append(n.EntryList, sha256.Sum256([]byte(fmt.Sprint("entry ", i))))
+	}
+	if err := n.Put(db); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, entryHash := range n.EntryList {
+		loc, err := GetEntryLocation(db, entryHash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if loc.NodeHash != *n.GetHash() || loc.BHeight != n.BHeight || loc.Position != i {
+			t.Errorf("entry %d found in node %x at height %d position %d", i, loc.NodeHash, loc.BHeight, loc.Position)
+		}
+		if !loc.Node.SameAs(*n) {
+			t.Error("the node found for the entry should be the node written")
+		}
+	}
+
+	if _, err := GetEntryLocation(db, types.Hash(sha256.Sum256([]byte("not an entry")))); err == nil {
+		t.Error("should not find an entry never recorded")
+	}
+}
@@ -40,9 +40,10 @@ type NEList struct {
 }
 
 // Put
-// Put this node into the database.  There is a little special treatment for the Directory Blocks.
-// In that case, the ChainID is the DID for the root Accumulator, and there are no SubChainIDs.
-func (n Node) Put(db *database.DB) error {
+// Put this node into the database, or into a batch to be committed to the database.  There is a little
+// special treatment for the Directory Blocks.  In that case, the ChainID is the DID for the root
+// Accumulator, and there are no SubChainIDs.
+func (n Node) Put(db database.Store) error {
 	nHash := n.GetHash()[:]
 
 	// So first do some indexing around the chain of nodes for this ChainID.  Set nodeFirst, nodeNext, nodeHead
@@ -52,22 +53,54 @@ func (n Node) Put(db *database.DB) error {
 	if headHash == nil && n.SequenceNum != 0 { // If that's nil, and our sequence number isn't zero, bad stuff is about!
 		return errors.New(fmt.Sprintf("chainID %x not found in DB, with sequence number %d", n.ChainID, n.SequenceNum))
 	} else if headHash == nil { // If we have no previous hash and our sequence number is zero, this is our first!
-		db.Put(types.NodeFirst, n.ChainID[:], nHash)
+		if err := db.Put(types.NodeFirst, n.ChainID[:], nHash); err != nil {
+			return err
+		}
 	} else { // Otherwise if I have a previous hash, then create an index from it to this node
-		db.Put(types.NodeNext, headHash, nHash)
+		if err := db.Put(types.NodeNext, headHash, nHash); err != nil {
+			return err
+		}
+	}
+	if err := db.Put(types.NodeHead, n.ChainID.Bytes(), nHash); err != nil {
+		return err
+	}
+
+	// Index every entry in an entry node against the node, so we can find where an entry is recorded
+	// (and reject it if it is submitted again).
+	if !n.IsNode {
+		for _, entryHash := range n.EntryList {
+			if err := db.Put(types.EntryNode, entryHash.Bytes(), nHash); err != nil {
+				return err
+			}
+		}
 	}
-	db.Put(types.NodeHead, n.ChainID.Bytes(), nHash)
 
 	// If a node does not have any SubChains to define its ChainID, then its ChainID is really
 	// the DID for the root accumulator, and this is a Directory Block.  So we will index it
-	// against the block height.  Other nodes are not indexed by block height.
-	if len(n.SubChainIDs) == 0 {
-		db.PutInt32(types.DirectoryBlockHeight, int(n.BHeight), nHash)
+	// against the block height.  Other nodes are not indexed by block height.  (Entry nodes
+	// may not have SubChains either, but they are not nodes of nodes.)
+	if n.IsNode && len(n.SubChainIDs) == 0 {
+		if err := db.PutInt32(types.DirectoryBlockHeight, int(n.BHeight), nHash); err != nil {
+			return err
+		}
 	}
 
-	db.Put(types.Node, nHash, n.Marshal()) // And of course, store the actual content.  Only in one place in the DB
+	// And of course, store the actual content.  Only in one place in the DB
+	return n.PutSubNode(db)
+}
 
-	return nil
+// PutSubNode
+// Put an intermediate node into the database, or into a batch.  Intermediate nodes sit between the
+// Directory Block and the chain nodes, and cover a range of chains in one block.  They are not part of any
+// chain of nodes, so none of the chain indexes are updated.  The node is stored by its hash, and indexed by
+// its MDRoot, so the node behind any NEList can be found.  Put calls this to store every other node too.
+func (n Node) PutSubNode(db database.Store) error {
+	n.MarshalCache = n.Marshal() // Marshal once for both the hash and the content
+	nHash := n.GetHash()[:]
+	if err := db.Put(types.MDRootNode, n.GetMDRoot().Bytes(), nHash); err != nil {
+		return err
+	}
+	return db.Put(types.Node, nHash, n.MarshalCache)
 }
 
 // SameAs
@@ -236,10 +269,10 @@ func (n *Node) Unmarshal(data []byte) (dataConsumed int, err error) {
 		n.List = append(n.List, *ne)
 	}
 	var eListLen uint32
-	listLen, data = types.BytesUint32(data)
+	eListLen, data = types.BytesUint32(data)
 	for i := uint32(0); i < eListLen; i++ {
 		var eHash types.Hash
-		eHash.Extract(data)
+		data = eHash.Extract(data)
 		n.EntryList = append(n.EntryList, eHash)
 	}
 
@@ -5,14 +5,13 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
-	"io/ioutil"
-	"os"
 	"testing"
 	"time"
 
 	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/database"
 
 	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
+	dbm "github.com/tendermint/tm-db"
 )
 
 // GetTestNode
@@ -80,14 +79,8 @@ func TestNode(t *testing.T) {
 // GetTestDB
 // Helper function for other tests to get a Test DB for running tests against a physical database
 func GetTestDB(t *testing.T) *database.DB {
-	dName, e := ioutil.TempDir("", "sampleDir")
-	if e != nil {
-		t.Fatal(e)
-	}
-	defer os.RemoveAll(dName)
 	db := new(database.DB)
-	db.DBHome = dName
-	db.Init(0)
+	db.InitDB(dbm.NewMemDB())
 	return db
 }
 
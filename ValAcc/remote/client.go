@@ -0,0 +1,240 @@
+package remote
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/FactomProject/factomd/util/atomic"
+
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/accumulator"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/node"
+)
+
+// Client
+// The router's end of the connection to an accumulator served by another process.  A Client looks to the
+// router just like an accumulator running in this process: entries are sent to its entry feed, EndBlock
+// seals a block and returns the BlockResult, and Run moves the entries until the context is done.
+type Client struct {
+	Addr      string                             // Address of the accumulator's Server
+	EntryCnt  atomic.AtomicInt64                 // Count of entries recorded by the remote accumulator
+	ChainCnt  atomic.AtomicInt64                 // Count of chains written to by the remote accumulator
+	conn      net.Conn                           // Connection to the Server
+	entryFeed chan node.EntryHash                // Entries waiting to be sent
+	endBlock  chan chan *accumulator.BlockResult // Requests to end the block, answered with the BlockResult
+	credits   chan int                           // Credits granted by the Server
+	results   chan *accumulator.BlockResult      // BlockResults sent by the Server
+	readErr   chan error                         // Why reading from the Server stopped
+	done      chan struct{}                      // Closed when Run returns
+}
+
+// Dial
+// Connect to the accumulator served at the given address
+func Dial(ctx context.Context, addr string) (*Client, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	c := new(Client)
+	c.Addr = addr
+	c.conn = conn
+	c.entryFeed = make(chan node.EntryHash, DefaultWindow)
+	c.endBlock = make(chan chan *accumulator.BlockResult)
+	c.credits = make(chan int, 16)
+	c.results = make(chan *accumulator.BlockResult, 1)
+	c.readErr = make(chan error, 1)
+	c.done = make(chan struct{})
+	return c, nil
+}
+
+// GetEntryFeed
+// Returns the channel taking the entries to send to the accumulator
+func (c *Client) GetEntryFeed() chan node.EntryHash {
+	return c.entryFeed
+}
+
+// Counts
+// Returns the counts of the remote accumulator (see accumulator.Accumulator.Counts), as of the last block
+// sealed through this Client
+func (c *Client) Counts() (entries, chains int64) {
+	return c.EntryCnt.Load(), c.ChainCnt.Load()
+}
+
+// Close
+// Hang up on an accumulator without running the Client.  Once Run is called, it closes the connection itself.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// EndBlock
+// Seal the block on the remote accumulator and return its BlockResult.  Every entry in the entry feed
+// when EndBlock is called is sent first, so it is included in the block.  Returns an error if the context
+// is done first, if the Client has stopped, or if the accumulator failed to seal the block.
+func (c *Client) EndBlock(ctx context.Context) (*accumulator.BlockResult, error) {
+	request := make(chan *accumulator.BlockResult, 1) // Buffered, so Run never stalls if we walk away
+	select {
+	case c.endBlock <- request:
+	case <-c.done:
+		return nil, errors.New(fmt.Sprintf("the connection to the accumulator at %s has stopped", c.Addr))
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	select {
+	case result := <-request:
+		return result, result.Err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Run
+// Send the entries in the entry feed to the accumulator as credits allow, and seal blocks when asked,
+// until the context is done.  Then the entries left in the feed are sent, the block in flight (if any
+// entries were sent since the last block) is sealed, and the connection is closed.  Returns any error
+// talking to the accumulator.
+func (c *Client) Run(ctx context.Context) error {
+	defer close(c.done)
+	defer c.conn.Close()
+	go c.read()
+
+	credit := 0
+	pending := false // True if entries were sent since the last block was sealed
+	for {
+		feed := c.entryFeed
+		if credit == 0 {
+			feed = nil // Wait for the Server to take some entries before sending more
+		}
+		select {
+		case <-ctx.Done():
+			if !pending && len(c.entryFeed) == 0 {
+				return nil
+			}
+			result := c.seal(&credit)
+			return result.Err
+		case request := <-c.endBlock:
+			result := c.seal(&credit)
+			pending = false
+			request <- result
+			if result.Err != nil && !c.connected() {
+				return result.Err
+			}
+		case n := <-c.credits:
+			credit += n
+		case err := <-c.readErr:
+			return err
+		case entry := <-feed:
+			if err := c.send(entry, &credit); err != nil {
+				return err
+			}
+			pending = true
+		}
+	}
+}
+
+// connected
+// Returns false once reading from the Server has failed
+func (c *Client) connected() bool {
+	select {
+	case err := <-c.readErr:
+		c.readErr <- err // Leave it for Run
+		return false
+	default:
+		return true
+	}
+}
+
+// send
+// Send the given entry, along with as many of the entries waiting in the feed as credit allows
+func (c *Client) send(entry node.EntryHash, credit *int) error {
+	batch := []node.EntryHash{entry}
+	for len(batch) < *credit && len(batch) < MaxBatch && len(c.entryFeed) > 0 {
+		batch = append(batch, <-c.entryFeed)
+	}
+	*credit -= len(batch)
+	return writeFrame(c.conn, MsgEntries, marshalEntries(batch))
+}
+
+// seal
+// Send every entry in the feed, waiting on credits as needed, then ask the accumulator to seal the block,
+// and wait for the BlockResult.
+func (c *Client) seal(credit *int) *accumulator.BlockResult {
+	fail := func(err error) *accumulator.BlockResult {
+		return &accumulator.BlockResult{Err: err}
+	}
+	for len(c.entryFeed) > 0 {
+		for *credit == 0 {
+			select {
+			case n := <-c.credits:
+				*credit += n
+			case err := <-c.readErr:
+				c.readErr <- err
+				return fail(err)
+			}
+		}
+		if err := c.send(<-c.entryFeed, credit); err != nil {
+			return fail(err)
+		}
+	}
+	if err := writeFrame(c.conn, MsgEndBlock, nil); err != nil {
+		return fail(err)
+	}
+	for {
+		select {
+		case n := <-c.credits:
+			*credit += n
+		case result := <-c.results:
+			if result.Err == nil { // The accumulator's own counts, so a Client counts just as an Accumulator does
+				c.EntryCnt.Store(result.TotalEntries)
+				c.ChainCnt.Store(result.TotalChains)
+			}
+			return result
+		case err := <-c.readErr:
+			c.readErr <- err
+			return fail(err)
+		}
+	}
+}
+
+// read
+// Read the frames sent by the Server, passing on credits and BlockResults, until the connection fails
+func (c *Client) read() {
+	reader := bufio.NewReader(c.conn)
+	for {
+		msgType, payload, err := readFrame(reader)
+		if err == nil {
+			switch msgType {
+			case MsgCredit:
+				var n int
+				if n, err = unmarshalCredit(payload); err == nil {
+					select {
+					case c.credits <- n:
+					case <-c.done:
+						return
+					}
+				}
+			case MsgBlockResult:
+				result := new(accumulator.BlockResult)
+				if _, err = result.Unmarshal(payload); err == nil {
+					select {
+					case c.results <- result:
+					case <-c.done:
+						return
+					}
+				}
+			default:
+				err = errors.New(fmt.Sprintf("unknown message type %d", msgType))
+			}
+		}
+		if err != nil {
+			select {
+			case <-c.done: // Run closed the connection
+			default:
+				c.readErr <- errors.New(fmt.Sprintf("reading from the accumulator at %s: %v", c.Addr, err))
+			}
+			return
+		}
+	}
+}
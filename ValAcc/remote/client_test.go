@@ -0,0 +1,158 @@
+package remote
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/accumulator"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/database"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/node"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
+	dbm "github.com/tendermint/tm-db"
+)
+
+// startTestServer
+// Serve an accumulator over an in memory database on a loopback port.  Returns the address served, the
+// accumulator, and a channel returning the error from Serve.
+func startTestServer(t *testing.T, ctx context.Context, i int, window int) (string, *accumulator.Accumulator, chan error) {
+	db := new(database.DB)
+	db.InitDB(dbm.NewMemDB())
+	chainID := types.Hash(sha256.Sum256([]byte(fmt.Sprintf("Accumulator %d", i))))
+	acc := new(accumulator.Accumulator)
+	acc.Init(db, &chainID)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	served := make(chan error, 1)
+	go func() {
+		served <- NewServer(acc, window).Serve(ctx, listener)
+	}()
+	return listener.Addr().String(), acc, served
+}
+
+func TestClientServer(t *testing.T) {
+	const numAcc = 3
+	serverCtx, stopServers := context.WithCancel(context.Background())
+	defer stopServers()
+	var accs []*accumulator.Accumulator
+	var served []chan error
+	var clients []*Client
+	clientCtx, stopClients := context.WithCancel(context.Background())
+	runErrs := make(chan error, numAcc)
+	for i := 0; i < numAcc; i++ {
+		addr, acc, s := startTestServer(t, serverCtx, i, 16) // A small window, so the router waits on credits
+		accs = append(accs, acc)
+		served = append(served, s)
+		client, err := Dial(context.Background(), addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		clients = append(clients, client)
+		go func() { runErrs <- client.Run(clientCtx) }()
+	}
+
+	entries := getTestEntries(3000)
+	for block := 0; block < 2; block++ {
+		for _, entry := range entries[block*1500 : (block+1)*1500] {
+			clients[int(entry.ChainID[0])%numAcc].GetEntryFeed() <- entry
+		}
+		if block == 1 { // Duplicates aren't recorded again, but the accumulators count them as they come in
+			for _, entry := range entries[:10] {
+				clients[int(entry.ChainID[0])%numAcc].GetEntryFeed() <- entry
+			}
+		}
+		var total int64
+		for i, client := range clients {
+			result, err := client.EndBlock(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Height != types.BlockHeight(block) {
+				t.Errorf("accumulator %d sealed height %d, expected %d", i, result.Height, block)
+			}
+			dBlock, err := node.GetNode(accs[i].DB, result.DirectoryBlock[:])
+			if err != nil {
+				t.Fatalf("the directory block returned isn't in the accumulator's database: %v", err)
+			}
+			if *dBlock.GetMDRoot() != result.MDRoot {
+				t.Error("the MDRoot returned doesn't match the directory block")
+			}
+			total += result.EntryCnt
+		}
+		if total != 1500 {
+			t.Errorf("expected 1500 entries recorded in block %d, got %d", block, total)
+		}
+	}
+	var total int64
+	for i, client := range clients {
+		entries, chains := client.Counts()
+		accEntries, accChains := accs[i].Counts()
+		if entries != accEntries || chains != accChains {
+			t.Errorf("client %d counts %d entries and %d chains, but its accumulator counts %d and %d",
+				i, entries, chains, accEntries, accChains)
+		}
+		total += entries
+	}
+	if total != 3010 {
+		t.Errorf("expected a count of 3010 entries, got %d", total)
+	}
+
+	// Entries sent just before shutting down are sealed in a last block
+	for _, entry := range getTestEntries(3100)[3000:] {
+		clients[int(entry.ChainID[0])%numAcc].GetEntryFeed() <- entry
+	}
+	stopClients()
+	for i := 0; i < numAcc; i++ {
+		if err := <-runErrs; err != nil {
+			t.Error(err)
+		}
+	}
+	total = 0
+	for _, client := range clients {
+		cnt, _ := client.Counts()
+		total += cnt
+	}
+	if total != 3110 {
+		t.Errorf("expected a count of 3110 entries after shutting down, got %d", total)
+	}
+	if _, err := clients[0].EndBlock(context.Background()); err == nil {
+		t.Error("EndBlock should fail once the client has stopped")
+	}
+
+	stopServers()
+	for i, s := range served {
+		if err := <-s; err != nil {
+			t.Errorf("accumulator %d failed to shut down cleanly: %v", i, err)
+		}
+	}
+}
+
+func TestServerGone(t *testing.T) {
+	serverCtx, stopServer := context.WithCancel(context.Background())
+	addr, _, served := startTestServer(t, serverCtx, 0, 0)
+	client, err := Dial(context.Background(), addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runErr := make(chan error, 1)
+	go func() { runErr <- client.Run(context.Background()) }()
+	if _, err := client.EndBlock(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	stopServer()
+	if err := <-served; err != nil {
+		t.Fatal(err)
+	}
+	if err := <-runErr; err == nil {
+		t.Error("the client should fail when the accumulator goes away")
+	}
+	if _, err := client.EndBlock(context.Background()); err == nil {
+		t.Error("EndBlock should fail when the accumulator has gone away")
+	}
+}
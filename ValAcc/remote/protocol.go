@@ -0,0 +1,117 @@
+package remote
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/node"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
+)
+
+// The wire protocol between a router and an accumulator running in another process.  The router dials the
+// accumulator's Server, and the two exchange frames over the TCP connection.  Every frame is a type byte,
+// the length of the payload as a uint32, and the payload:
+//
+//    Entries     router -> accumulator   count uint32, then that many marshaled EntryHashes
+//    EndBlock    router -> accumulator   no payload; seal the block once the entries sent before it are added
+//    Credit      accumulator -> router   count uint32 of entries the router may send
+//    BlockResult accumulator -> router   a marshaled BlockResult, answering an EndBlock
+//
+// Flow control is by credit.  On connecting, the Server grants a window of credits, and the router sends
+// no more entries than it holds credits for.  The Server grants the credits back as the entries are taken
+// by the accumulator, so a slow accumulator pushes back on the router rather than buffering without bound.
+
+const (
+	MsgEntries     byte = iota + 1 // A batch of EntryHashes
+	MsgEndBlock                    // A request to seal the block
+	MsgCredit                      // A grant of credits to send entries
+	MsgBlockResult                 // The result of sealing a block
+)
+
+// MaxFrame
+// The largest payload accepted in a frame.  Anything larger is a broken or hostile peer.
+const MaxFrame = 64 << 20
+
+// DefaultWindow
+// The credits granted by a Server when none are configured; the size of an accumulator's entry feed
+const DefaultWindow = 10000
+
+// MaxBatch
+// The most entries sent in one Entries frame
+const MaxBatch = 1000
+
+// writeFrame
+// Write a frame of the given type and payload
+func writeFrame(w io.Writer, msgType byte, payload []byte) error {
+	frame := make([]byte, 0, 5+len(payload))
+	frame = append(frame, msgType)
+	frame = append(frame, types.Uint32Bytes(uint32(len(payload)))...)
+	frame = append(frame, payload...)
+	_, err := w.Write(frame)
+	return err
+}
+
+// readFrame
+// Read the next frame, returning its type and payload.  Returns io.EOF if the connection was closed
+// between frames.
+func readFrame(r io.Reader) (msgType byte, payload []byte, err error) {
+	var header [5]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	length, _ := types.BytesUint32(header[1:])
+	if length > MaxFrame {
+		return 0, nil, errors.New(fmt.Sprintf("frame of %d bytes is larger than the limit of %d", length, MaxFrame))
+	}
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, nil, err
+	}
+	return header[0], payload, nil
+}
+
+// marshalEntries
+// The payload of an Entries frame
+func marshalEntries(entries []node.EntryHash) (payload []byte) {
+	payload = append(payload, types.Uint32Bytes(uint32(len(entries)))...)
+	for _, entry := range entries {
+		payload = append(payload, entry.Marshal()...)
+	}
+	return payload
+}
+
+// unmarshalEntries
+// Extract the EntryHashes from the payload of an Entries frame
+func unmarshalEntries(payload []byte) (entries []node.EntryHash, err error) {
+	if len(payload) < 4 {
+		return nil, errors.New("entries frame is too short")
+	}
+	count, data := types.BytesUint32(payload)
+	for i := uint32(0); i < count; i++ {
+		var entry node.EntryHash
+		n, err := entry.Unmarshal(data)
+		if err != nil {
+			return nil, err
+		}
+		data = data[n:]
+		entries = append(entries, entry)
+	}
+	if len(data) != 0 {
+		return nil, errors.New(fmt.Sprintf("entries frame has %d bytes left over", len(data)))
+	}
+	return entries, nil
+}
+
+// unmarshalCredit
+// Extract the count of credits from the payload of a Credit frame
+func unmarshalCredit(payload []byte) (int, error) {
+	if len(payload) != 4 {
+		return 0, errors.New(fmt.Sprintf("credit frame of %d bytes, expected 4", len(payload)))
+	}
+	credit, _ := types.BytesUint32(payload)
+	return int(credit), nil
+}
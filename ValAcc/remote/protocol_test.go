@@ -0,0 +1,91 @@
+package remote
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/node"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
+)
+
+// getTestEntries
+// Build the given number of entries spread over a few chains, some with SubChains
+func getTestEntries(cnt int) (entries []node.EntryHash) {
+	for i := 0; i < cnt; i++ {
+		var entry node.EntryHash
+		entry.ChainID = sha256.Sum256([]byte(fmt.Sprint("chain ", i%7)))
+		entry.EntryHash = sha256.Sum256([]byte(fmt.Sprint("entry ", i)))
+		if i%3 == 0 {
+			entry.SubChains = []types.Hash{sha256.Sum256([]byte(fmt.Sprint("sub chain ", i)))}
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestFrames(t *testing.T) {
+	entries := getTestEntries(10)
+	var buf bytes.Buffer
+	if err := writeFrame(&buf, MsgEntries, marshalEntries(entries)); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFrame(&buf, MsgEndBlock, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFrame(&buf, MsgCredit, types.Uint32Bytes(42)); err != nil {
+		t.Fatal(err)
+	}
+
+	msgType, payload, err := readFrame(&buf)
+	if err != nil || msgType != MsgEntries {
+		t.Fatalf("expected an entries frame, got %d %v", msgType, err)
+	}
+	got, err := unmarshalEntries(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(entries) {
+		t.Fatalf("expected %d entries, got %d", len(entries), len(got))
+	}
+	for i := range entries {
+		if !bytes.Equal(got[i].Marshal(), entries[i].Marshal()) {
+			t.Errorf("entry %d doesn't match", i)
+		}
+	}
+	if msgType, payload, err = readFrame(&buf); err != nil || msgType != MsgEndBlock || len(payload) != 0 {
+		t.Errorf("expected an empty end block frame, got %d %x %v", msgType, payload, err)
+	}
+	msgType, payload, err = readFrame(&buf)
+	if err != nil || msgType != MsgCredit {
+		t.Fatalf("expected a credit frame, got %d %v", msgType, err)
+	}
+	if credit, err := unmarshalCredit(payload); err != nil || credit != 42 {
+		t.Errorf("expected 42 credits, got %d %v", credit, err)
+	}
+	if _, _, err := readFrame(&buf); err != io.EOF {
+		t.Errorf("expected io.EOF between frames, got %v", err)
+	}
+
+	// Broken frames
+	frame := append([]byte{MsgEntries}, types.Uint32Bytes(100)...)
+	if _, _, err := readFrame(bytes.NewReader(append(frame, 1, 2, 3))); err != io.ErrUnexpectedEOF {
+		t.Errorf("expected io.ErrUnexpectedEOF for a truncated frame, got %v", err)
+	}
+	frame = append([]byte{MsgEntries}, types.Uint32Bytes(MaxFrame+1)...)
+	if _, _, err := readFrame(bytes.NewReader(frame)); err == nil {
+		t.Error("a frame over the limit should fail")
+	}
+	payload = marshalEntries(entries)
+	if _, err := unmarshalEntries(payload[:len(payload)-1]); err == nil {
+		t.Error("truncated entries should fail to unmarshal")
+	}
+	if _, err := unmarshalEntries(append(payload, 0)); err == nil {
+		t.Error("entries with data left over should fail to unmarshal")
+	}
+	if _, err := unmarshalCredit([]byte{1, 2}); err == nil {
+		t.Error("a short credit should fail to unmarshal")
+	}
+}
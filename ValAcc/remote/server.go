@@ -0,0 +1,143 @@
+package remote
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/accumulator"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
+)
+
+// Server
+// Runs an accumulator on behalf of a router in another process.  The Server takes one router connection at
+// a time, feeding the entries sent to the accumulator, and sending back the BlockResult of each block the
+// router asks it to seal.
+type Server struct {
+	Acc    *accumulator.Accumulator // The accumulator served; Serve runs it
+	Window int                      // Credits granted to the router; DefaultWindow if zero
+}
+
+// NewServer
+// Create a Server for the given accumulator, which must have been through Init
+func NewServer(acc *accumulator.Accumulator, window int) *Server {
+	s := new(Server)
+	s.Acc = acc
+	s.Window = window
+	return s
+}
+
+// Serve
+// Run the accumulator, and serve routers connecting on the listener until the context is done.  Then the
+// listener and any router connection are closed, and the accumulator seals its block in flight and closes
+// its database before Serve returns.
+func (s *Server) Serve(ctx context.Context, listener net.Listener) error {
+	accCtx, stopAcc := context.WithCancel(context.Background())
+	accErr := make(chan error, 1)
+	go func() {
+		accErr <- s.Acc.Run(accCtx)
+	}()
+
+	var mutex sync.Mutex
+	var conn net.Conn // The router connected, if any
+	stopped := make(chan struct{})
+	defer close(stopped)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-stopped:
+		}
+		listener.Close()
+		mutex.Lock()
+		if conn != nil {
+			conn.Close()
+		}
+		mutex.Unlock()
+	}()
+
+	var err error
+	for {
+		c, aErr := listener.Accept()
+		if aErr != nil {
+			if ctx.Err() == nil {
+				err = aErr
+			}
+			break
+		}
+		mutex.Lock()
+		conn = c
+		mutex.Unlock()
+		if ctx.Err() != nil { // Closed down while accepting
+			c.Close()
+			break
+		}
+		hErr := s.handle(ctx, c) // One router at a time, so the order of entries and blocks is the router's
+		c.Close()
+		if hErr != nil && ctx.Err() == nil {
+			fmt.Printf("Router %s disconnected: %v\n", c.RemoteAddr(), hErr)
+		}
+		mutex.Lock()
+		conn = nil
+		mutex.Unlock()
+	}
+	stopAcc()
+	if runErr := <-accErr; runErr != nil && err == nil {
+		err = runErr
+	}
+	return err
+}
+
+// handle
+// Serve one router until it disconnects.  Frames are handled in the order received, so every entry sent
+// before an EndBlock is in the accumulator's feed when the block is sealed.
+func (s *Server) handle(ctx context.Context, conn net.Conn) error {
+	window := s.Window
+	if window <= 0 {
+		window = DefaultWindow
+	}
+	reader := bufio.NewReader(conn)
+	if err := writeFrame(conn, MsgCredit, types.Uint32Bytes(uint32(window))); err != nil {
+		return err
+	}
+	entryFeed := s.Acc.GetEntryFeed()
+	for {
+		msgType, payload, err := readFrame(reader)
+		if err == io.EOF {
+			return nil // The router hung up
+		}
+		if err != nil {
+			return err
+		}
+		switch msgType {
+		case MsgEntries:
+			entries, err := unmarshalEntries(payload)
+			if err != nil {
+				return err
+			}
+			for _, entry := range entries {
+				select {
+				case entryFeed <- entry:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			if err := writeFrame(conn, MsgCredit, types.Uint32Bytes(uint32(len(entries)))); err != nil {
+				return err
+			}
+		case MsgEndBlock:
+			result, err := s.Acc.EndBlock(ctx)
+			if result == nil {
+				result = &accumulator.BlockResult{Err: err}
+			}
+			if err := writeFrame(conn, MsgBlockResult, result.Marshal()); err != nil {
+				return err
+			}
+		default:
+			return errors.New(fmt.Sprintf("unknown message type %d", msgType))
+		}
+	}
+}
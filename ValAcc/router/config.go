@@ -0,0 +1,126 @@
+package router
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/accumulator"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/database"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
+)
+
+// Config
+// Where and how the router and its accumulators keep their databases.  Every database is found under the
+// DataDir, the router's at RouterPath, and each accumulator's at AccumulatorPath formatted with its index.
+// An accumulator's write-ahead log is kept in the same directory as its database.
+//
+// With the "memdb" backend nothing is written to disk, not even the write-ahead logs, so everything is lost
+// when the process ends.  This is meant for tests.
+//
+// Before the Config, the databases were kept in the working directory: the router's goleveldb database in
+// router.db/router.db.db, and each accumulator's in accumulator_<i>.db/accumulator_<i>.db.db.  A database
+// found there, with none yet under the DataDir, is not opened or copied; opening it fails with instructions
+// to move it, rather than starting over on an empty database.
+type Config struct {
+	Backend         string // tm-db backend of the databases (see database.Open)
+	DataDir         string // Directory holding all the databases
+	RouterPath      string // Subpath of the router's database under DataDir
+	AccumulatorPath string // Subpath of an accumulator's database under DataDir, formatted with its index
+	LegacyDir       string // Where the databases were kept before the Config; "" for the working directory
+}
+
+// DefaultConfig
+// Keep goleveldb databases in .ValAcc under the home directory (or under $VALACC if set, see
+// types.GetHomeDir)
+func DefaultConfig() *Config {
+	c := new(Config)
+	c.Backend = "goleveldb"
+	c.DataDir = filepath.Join(types.GetHomeDir(), ".ValAcc")
+	c.RouterPath = "router"
+	c.AccumulatorPath = "accumulator_%d"
+	return c
+}
+
+// MemoryConfig
+// Keep every database in memory, for tests
+func MemoryConfig() *Config {
+	c := DefaultConfig()
+	c.Backend = "memdb"
+	return c
+}
+
+// InMemory
+// Returns true if nothing is written to disk
+func (c *Config) InMemory() bool {
+	return c.Backend == "memdb"
+}
+
+// AccumulatorDir
+// The directory holding the database and write-ahead log of the accumulator with the given index
+func (c *Config) AccumulatorDir(i int) string {
+	return filepath.Join(c.DataDir, fmt.Sprintf(c.AccumulatorPath, i))
+}
+
+// checkLegacy
+// Returns an error if the database isn't in the DataDir yet, but the same database is in the LegacyDir, where
+// it was kept before the Config.  The error says how to move it.
+func (c *Config) checkLegacy(dir, name, legacyName string) error {
+	if c.InMemory() || database.Exists(c.Backend, dir, name) == nil {
+		return nil
+	}
+	legacy := filepath.Join(c.LegacyDir, legacyName, legacyName+".db")
+	if _, err := os.Stat(legacy); err != nil {
+		return nil
+	}
+	return errors.New(fmt.Sprintf("there is no database at %s, but there is one from an earlier version at %s.  "+
+		"Databases are now kept under the data directory.  Move it with\n\tmkdir -p %s && mv %s %s\n"+
+		"or remove it to start over", dir, legacy, dir, legacy, filepath.Join(dir, name+".db")))
+}
+
+// OpenRouterDB
+// Open the router's database, holding the global roots and the shard strategy
+func (c *Config) OpenRouterDB() (*database.DB, error) {
+	dir := filepath.Join(c.DataDir, c.RouterPath)
+	if err := c.checkLegacy(dir, "router", "router.db"); err != nil {
+		return nil, err
+	}
+	db, err := database.Open(c.Backend, dir, "router")
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("failed to open the router database: %v", err))
+	}
+	return db, nil
+}
+
+// OpenAccumulatorDB
+// Open the database of the accumulator with the given index
+func (c *Config) OpenAccumulatorDB(i int) (*database.DB, error) {
+	if err := c.checkLegacy(c.AccumulatorDir(i), "accumulator", fmt.Sprintf("accumulator_%d.db", i)); err != nil {
+		return nil, err
+	}
+	db, err := database.Open(c.Backend, c.AccumulatorDir(i), "accumulator")
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("failed to open the database of accumulator %d: %v", i, err))
+	}
+	return db, nil
+}
+
+// OpenAccumulator
+// Open the database of the accumulator with the given index, and start the accumulator on it
+func (c *Config) OpenAccumulator(i int) (*accumulator.Accumulator, error) {
+	db, err := c.OpenAccumulatorDB(i)
+	if err != nil {
+		return nil, err
+	}
+	chainID := types.Hash(sha256.Sum256([]byte(fmt.Sprintf("Accumulator %d", i))))
+
+	acc := new(accumulator.Accumulator)
+	if !c.InMemory() {
+		acc.WALPath = filepath.Join(c.AccumulatorDir(i), "accumulator.wal") // Keep the write-ahead log with the database
+	}
+	acc.Fanout = accumulator.DefaultFanout
+	acc.Init(db, &chainID)
+	return acc, nil
+}
@@ -0,0 +1,158 @@
+package router
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/accumulator"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/database"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/node"
+)
+
+func TestMemoryConfig(t *testing.T) {
+	r := &Router{Config: MemoryConfig()}
+	r.Config.DataDir = filepath.Join(t.TempDir(), "data")
+	if err := r.Init(make(chan node.EntryHash), 3); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.ACCs) != 3 || len(r.DBs) != 3 {
+		t.Errorf("expected 3 accumulators, got %d", len(r.ACCs))
+	}
+	if _, err := os.Stat(r.Config.DataDir); !os.IsNotExist(err) {
+		t.Error("nothing should be written to disk in memory")
+	}
+}
+
+func TestConfigRestart(t *testing.T) {
+	config := DefaultConfig()
+	config.DataDir = t.TempDir()
+
+	// Record a block over two accumulators
+	r := &Router{Config: config, Policy: &BlockPolicy{}}
+	stream := make(chan node.EntryHash)
+	if err := r.Init(stream, 2); err != nil {
+		t.Fatal(err)
+	}
+	ctx, shutdown := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		r.Run(ctx)
+		close(done)
+	}()
+	for i := 0; i < 100; i++ {
+		stream <- getTestEntry(i, 10)
+	}
+	if _, err := r.CloseBlock(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	shutdown()
+	<-done
+	for _, path := range []string{"router/router.db", "accumulator_0/accumulator.db", "accumulator_1/accumulator.wal"} {
+		if _, err := os.Stat(filepath.Join(config.DataDir, path)); err != nil {
+			t.Errorf("expected %s under the data directory: %v", path, err)
+		}
+	}
+
+	// Restart over three, moving the chains
+	r = &Router{Config: config}
+	if err := r.Init(stream, 3); err != nil {
+		t.Fatal(err)
+	}
+	if r.Strategy.Count() != 3 {
+		t.Errorf("expected the strategy to route to 3 accumulators, got %d", r.Strategy.Count())
+	}
+	total := 0
+	for i, acc := range r.ACCs {
+		held, err := acc.(*accumulator.Accumulator).Chains()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, chainID := range held {
+			if r.Strategy.Route(chainID) != i {
+				t.Errorf("chain %x is in accumulator %d, but is routed to %d", chainID[:4], i, r.Strategy.Route(chainID))
+			}
+		}
+		total += len(held)
+		if err := acc.(*accumulator.Accumulator).Close(); err != nil {
+			t.Error(err)
+		}
+	}
+	if total != 10 {
+		t.Errorf("expected 10 chains after the restart, found %d", total)
+	}
+	if err := r.DB.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConfigErrors(t *testing.T) {
+	config := DefaultConfig()
+	config.DataDir = t.TempDir()
+	config.Backend = "nosuchdb"
+	if err := (&Router{Config: config}).Init(make(chan node.EntryHash), 2); err == nil {
+		t.Error("an unknown backend should fail")
+	}
+
+	// A file where the data directory should be
+	config.Backend = "goleveldb"
+	config.DataDir = filepath.Join(config.DataDir, "file")
+	if err := ioutil.WriteFile(config.DataDir, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := (&Router{Config: config}).Init(make(chan node.EntryHash), 2); err == nil {
+		t.Error("a data directory that can't be created should fail")
+	}
+
+	if err := (&Router{Config: MemoryConfig()}).Init(make(chan node.EntryHash), 0); err == nil {
+		t.Error("a router without accumulators should fail")
+	}
+}
+
+func TestLegacyDatabases(t *testing.T) {
+	config := DefaultConfig()
+	config.DataDir = filepath.Join(t.TempDir(), "data")
+	config.LegacyDir = t.TempDir()
+
+	// An accumulator database where it was kept before the Config
+	legacyDir := filepath.Join(config.LegacyDir, "accumulator_0.db")
+	old, err := database.Open(config.Backend, legacyDir, "accumulator_0.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	old.Put("test", []byte("key"), []byte("value"))
+	old.Close()
+
+	if _, err := config.OpenAccumulatorDB(0); err == nil || !strings.Contains(err.Error(), legacyDir) {
+		t.Fatalf("opening the accumulator should fail, pointing to the database at its old path, got %v", err)
+	}
+	if _, err := os.Stat(config.AccumulatorDir(0)); !os.IsNotExist(err) {
+		t.Error("no new database should be created while the old one is in the way")
+	}
+	db, err := config.OpenAccumulatorDB(1) // Nothing of accumulator 1 was kept before
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	// Moved as the error says, the database is found
+	os.MkdirAll(config.AccumulatorDir(0), 0755)
+	if err := os.Rename(filepath.Join(legacyDir, "accumulator_0.db.db"), filepath.Join(config.AccumulatorDir(0), "accumulator.db")); err != nil {
+		t.Fatal(err)
+	}
+	if db, err = config.OpenAccumulatorDB(0); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if string(db.Get("test", []byte("key"))) != "value" {
+		t.Error("the database moved should be the one opened")
+	}
+
+	os.MkdirAll(filepath.Join(config.LegacyDir, "router.db", "router.db.db"), 0755)
+	if _, err := config.OpenRouterDB(); err == nil {
+		t.Error("opening the router should fail while its database is at its old path")
+	}
+}
@@ -0,0 +1,164 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/accumulator"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/anchor"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/database"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/merkleDag"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
+)
+
+// GlobalRoot
+// The root of roots for a block height.  The directory block MDRoot of every accumulator at the height is
+// added to an MD in the order of the router's accumulators, and the MDRoot of that MD is the one value that
+// represents the state of the whole system at the height.
+type GlobalRoot struct {
+	Height types.BlockHeight // Block height of the directory blocks
+	Roots  []types.Hash      // Directory block MDRoot of each accumulator, in accumulator order
+	MDRoot types.Hash        // MDRoot of the Roots
+}
+
+// NewGlobalRoot
+// Build the GlobalRoot from the results of sealing a block on every accumulator, in accumulator order.
+// Returns an error if any accumulator failed to seal its block, or the accumulators sealed different heights.
+func NewGlobalRoot(results []*accumulator.BlockResult) (*GlobalRoot, error) {
+	if len(results) == 0 {
+		return nil, errors.New("no accumulators to build a global root")
+	}
+	g := new(GlobalRoot)
+	g.Height = results[0].Height
+	md := new(merkleDag.MD)
+	for i, result := range results {
+		if result.Err != nil {
+			return nil, errors.New(fmt.Sprintf("accumulator %d failed to seal its block: %v", i, result.Err))
+		}
+		if result.Height != g.Height {
+			return nil, errors.New(fmt.Sprintf("accumulator %d sealed height %d, not %d", i, result.Height, g.Height))
+		}
+		g.Roots = append(g.Roots, result.MDRoot)
+		md.AddToChain(result.MDRoot)
+	}
+	g.MDRoot = *md.GetMDRoot()
+	return g, nil
+}
+
+// Receipt
+// Build the receipt from the directory block root of the given accumulator to the global root.  This is the
+// last hop after the accumulator's CompositeReceipt.
+func (g *GlobalRoot) Receipt(accIndex int) (*merkleDag.MDReceipt, error) {
+	if accIndex < 0 || accIndex >= len(g.Roots) {
+		return nil, errors.New(fmt.Sprintf("no accumulator %d at height %d", accIndex, g.Height))
+	}
+	md := new(merkleDag.MD)
+	for _, root := range g.Roots {
+		md.AddToChain(root)
+	}
+	mdr := new(merkleDag.MDReceipt)
+	mdr.BuildMDReceipt(*md, g.Roots[accIndex])
+	return mdr, nil
+}
+
+// AnchorProof
+// Proof that a directory block root was anchored.  The Receipt proves the directory block root against the
+// global root of its height, and the Record proves that global root against the root anchored in the
+// external transaction.  Together with an accumulator's CompositeReceipt, an entry is proven all the way to
+// the anchor.
+type AnchorProof struct {
+	Height  types.BlockHeight   // Block height of the directory block
+	Receipt merkleDag.MDReceipt // Proof of the directory block root against the global root
+	Record  anchor.Record       // Proof of the global root against the anchored root
+}
+
+// Validate
+// Check both receipts, and that the first proves the global root proven by the second
+func (p *AnchorProof) Validate() bool {
+	return p.Receipt.Validate() && p.Record.Validate() && p.Receipt.MDRoot == p.Record.GlobalRoot
+}
+
+// GetAnchorProof
+// Build the proof that the directory block root of the given accumulator at the given height was anchored,
+// from the global roots and anchor records in the router's database.  Returns an error if there is no global
+// root at the height, or it has not been anchored yet.
+func GetAnchorProof(db *database.DB, height types.BlockHeight, accIndex int) (*AnchorProof, error) {
+	g, err := GetGlobalRoot(db, height)
+	if err != nil {
+		return nil, err
+	}
+	receipt, err := g.Receipt(accIndex)
+	if err != nil {
+		return nil, err
+	}
+	record, err := anchor.GetRecord(db, g.MDRoot)
+	if err != nil {
+		return nil, err
+	}
+	p := new(AnchorProof)
+	p.Height = height
+	p.Receipt = *receipt
+	p.Record = *record
+	if !p.Validate() {
+		return nil, errors.New(fmt.Sprintf("the anchor proof for accumulator %d at height %d does not validate", accIndex, height))
+	}
+	return p, nil
+}
+
+// Put
+// Write the GlobalRoot to the database, keyed by its height
+func (g *GlobalRoot) Put(db database.Store) error {
+	return db.PutInt32(types.GlobalRoot, int(g.Height), g.Marshal())
+}
+
+// GetGlobalRoot
+// Get the GlobalRoot at the given height.  Returns an error if there is none.
+func GetGlobalRoot(db *database.DB, height types.BlockHeight) (*GlobalRoot, error) {
+	data := db.GetInt32(types.GlobalRoot, uint32(height))
+	if data == nil {
+		return nil, errors.New(fmt.Sprintf("no global root at height %d", height))
+	}
+	g := new(GlobalRoot)
+	if _, err := g.Unmarshal(data); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// Marshal
+// The binary encoding of a GlobalRoot
+func (g *GlobalRoot) Marshal() (data []byte) {
+	data = append(data, g.Height.Bytes()...)
+	data = append(data, types.Uint32Bytes(uint32(len(g.Roots)))...)
+	for _, root := range g.Roots {
+		data = append(data, root.Bytes()...)
+	}
+	data = append(data, g.MDRoot.Bytes()...)
+	return data
+}
+
+// Unmarshal
+// Extract a GlobalRoot from its binary encoding.  Returns an error if the unmarshal fails, or the length of
+// the data consumed and a nil.
+func (g *GlobalRoot) Unmarshal(data []byte) (dataConsumed int, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New(fmt.Sprintf("GlobalRoot Failed to unmarshal %v", r))
+		}
+	}()
+	d := data
+	data = g.Height.Extract(data)
+	var numRoots uint32
+	numRoots, data = types.BytesUint32(data)
+	if uint64(numRoots)*32 > uint64(len(data)) {
+		return 0, errors.New(fmt.Sprintf("GlobalRoot claims %d roots, more than the data holds", numRoots))
+	}
+	g.Roots = g.Roots[:0]
+	for i := uint32(0); i < numRoots; i++ {
+		var root types.Hash
+		data = root.Extract(data)
+		g.Roots = append(g.Roots, root)
+	}
+	data = g.MDRoot.Extract(data)
+	return len(d) - len(data), nil
+}
@@ -0,0 +1,145 @@
+package router
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/accumulator"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/anchor"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/database"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
+	dbm "github.com/tendermint/tm-db"
+)
+
+// getTestResults
+// Build the BlockResults of the given number of accumulators sealing the given height
+func getTestResults(cnt int, height types.BlockHeight) (results []*accumulator.BlockResult) {
+	for i := 0; i < cnt; i++ {
+		result := new(accumulator.BlockResult)
+		result.Height = height
+		result.MDRoot = sha256.Sum256([]byte(fmt.Sprint("accumulator ", i, " height ", height)))
+		results = append(results, result)
+	}
+	return results
+}
+
+func TestGlobalRoot(t *testing.T) {
+	db := new(database.DB)
+	db.InitDB(dbm.NewMemDB())
+
+	for height := types.BlockHeight(0); height < 3; height++ {
+		results := getTestResults(5, height)
+		g, err := NewGlobalRoot(results)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := g.Put(db); err != nil {
+			t.Fatal(err)
+		}
+		for i, result := range results {
+			mdr, err := g.Receipt(i)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if mdr.EntryHash != result.MDRoot || mdr.MDRoot != g.MDRoot || !mdr.Validate() {
+				t.Errorf("the receipt for accumulator %d should prove its root against the global root", i)
+			}
+		}
+		if _, err := g.Receipt(5); err == nil {
+			t.Error("there is no accumulator 5")
+		}
+	}
+
+	g, err := GetGlobalRoot(db, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, _ := NewGlobalRoot(getTestResults(5, 1))
+	if g.Height != 1 || g.MDRoot != expected.MDRoot || len(g.Roots) != 5 {
+		t.Error("the global root read back should be the one written")
+	}
+	if _, err := GetGlobalRoot(db, 3); err == nil {
+		t.Error("there is no global root at height 3")
+	}
+
+	// The order of the accumulators matters
+	results := getTestResults(5, 0)
+	results[0], results[1] = results[1], results[0]
+	if swapped, _ := NewGlobalRoot(results); swapped.MDRoot == expected.MDRoot {
+		t.Error("the global root should depend on the order of the accumulators")
+	}
+
+	results = getTestResults(5, 0)
+	results[2].Err = errors.New("failed")
+	if _, err := NewGlobalRoot(results); err == nil {
+		t.Error("no global root should be built if an accumulator failed")
+	}
+	results = getTestResults(5, 0)
+	results[3].Height = 1
+	if _, err := NewGlobalRoot(results); err == nil {
+		t.Error("no global root should be built over different heights")
+	}
+	if _, err := NewGlobalRoot(nil); err == nil {
+		t.Error("no global root should be built without accumulators")
+	}
+}
+
+func TestAnchorProof(t *testing.T) {
+	db := new(database.DB)
+	db.InitDB(dbm.NewMemDB())
+	anchorer, err := anchor.NewFileAnchorer(filepath.Join(t.TempDir(), "anchors.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	scheduler := anchor.NewScheduler(db, anchorer, 100, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go scheduler.Run(ctx)
+
+	for height := types.BlockHeight(0); height < 3; height++ {
+		g, _ := NewGlobalRoot(getTestResults(4, height))
+		g.Put(db)
+		if height == 1 {
+			if _, err := GetAnchorProof(db, height, 0); err == nil {
+				t.Error("there is no anchor proof until the global root is anchored")
+			}
+		}
+		if err := scheduler.Submit(ctx, g.MDRoot); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := scheduler.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	results := getTestResults(4, 1)
+	for i, result := range results {
+		p, err := GetAnchorProof(db, 1, i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !p.Validate() || p.Receipt.EntryHash != result.MDRoot {
+			t.Errorf("the anchor proof should prove the directory block root of accumulator %d", i)
+		}
+		anchored, err := anchorer.Lookup(p.Record.TxRef)
+		if err != nil || anchored != p.Record.AnchoredRoot {
+			t.Error("the anchor proof should end at the root anchored")
+		}
+	}
+
+	p, _ := GetAnchorProof(db, 1, 0)
+	p.Receipt.MDRoot[0]++ // A receipt to some other global root
+	if p.Validate() {
+		t.Error("the receipt must prove the global root of the record")
+	}
+	if _, err := GetAnchorProof(db, 3, 0); err == nil {
+		t.Error("there is no global root at height 3")
+	}
+	if _, err := GetAnchorProof(db, 1, 4); err == nil {
+		t.Error("there is no accumulator 4")
+	}
+}
@@ -0,0 +1,62 @@
+package router
+
+import (
+	"time"
+
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/node"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
+)
+
+// BlockPolicy
+// Decides when the router closes a block.  A block is closed as soon as any of the limits set is reached;
+// a zero turns a limit off.  Blocks can also be closed at any time by a call to CloseBlock, so a policy
+// with no limits at all leaves closing blocks to the caller.  Whatever closes a block, every accumulator
+// seals the same height together.
+//
+// The counts are of the entries routed into the block, including any the accumulators reject as duplicates,
+// so blocks closed by count hold the same entries every time the same stream is routed.
+type BlockPolicy struct {
+	Interval   time.Duration // Close the block when this long has passed since the last block closed
+	MaxEntries int64         // Close the block once this many entries are routed into it
+	MaxChains  int64         // Close the block once entries for this many distinct chains are routed into it
+	MaxBytes   int64         // Close the block once the marshaled entries routed into it reach this size
+}
+
+// DefaultBlockPolicy
+// Close a block every 10 seconds
+var DefaultBlockPolicy = BlockPolicy{Interval: 10 * time.Second}
+
+// blockTally
+// What has been routed into the block in flight, counted against the BlockPolicy
+type blockTally struct {
+	entries int64
+	chains  map[types.Hash]struct{}
+	bytes   int64
+}
+
+// add
+// Count an entry routed into the block
+func (t *blockTally) add(entry node.EntryHash) {
+	if t.chains == nil {
+		t.chains = make(map[types.Hash]struct{})
+	}
+	t.entries++
+	t.chains[entry.ChainID] = struct{}{}
+	t.bytes += int64(2 + len(entry.SubChains)*len(types.Hash{}) + 2*len(types.Hash{})) // len(entry.Marshal())
+}
+
+// reset
+// Start counting a new block
+func (t *blockTally) reset() {
+	t.entries = 0
+	t.chains = nil
+	t.bytes = 0
+}
+
+// full
+// Returns true if the policy says the block counted should be closed
+func (p *BlockPolicy) full(t *blockTally) bool {
+	return (p.MaxEntries > 0 && t.entries >= p.MaxEntries) ||
+		(p.MaxChains > 0 && int64(len(t.chains)) >= p.MaxChains) ||
+		(p.MaxBytes > 0 && t.bytes >= p.MaxBytes)
+}
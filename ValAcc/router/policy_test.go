@@ -0,0 +1,168 @@
+package router
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/database"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/node"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
+	dbm "github.com/tendermint/tm-db"
+)
+
+// getTestEntry
+// Build the i-th entry of a stream spread over the given number of chains
+func getTestEntry(i int, numChains int) (entry node.EntryHash) {
+	entry.ChainID = sha256.Sum256([]byte(fmt.Sprint("chain ", i%numChains)))
+	entry.EntryHash = sha256.Sum256([]byte(fmt.Sprint("entry ", i)))
+	return entry
+}
+
+// startTestRouter
+// Run a router over the given number of accumulators on in memory databases, with the given policy.  Returns
+// the router, and a func that shuts it down and waits for Run to return.
+func startTestRouter(numAcc int, policy *BlockPolicy) (*Router, func()) {
+	db := new(database.DB)
+	db.InitDB(dbm.NewMemDB())
+	r := &Router{DB: db, EntryHashStream: make(chan node.EntryHash), Strategy: &Modulo{Accumulators: numAcc}}
+	r.Policy = policy
+	for i := 0; i < numAcc; i++ {
+		accDB := new(database.DB)
+		accDB.InitDB(dbm.NewMemDB())
+		r.ACCs = append(r.ACCs, startTestAccumulator(accDB, i))
+	}
+	ctx, shutdown := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		r.Run(ctx)
+		close(done)
+	}()
+	return r, func() {
+		shutdown()
+		<-done
+	}
+}
+
+func TestBlockPolicyFull(t *testing.T) {
+	var tally blockTally
+	for i := 0; i < 10; i++ {
+		tally.add(getTestEntry(i, 4))
+	}
+	if tally.entries != 10 || len(tally.chains) != 4 || tally.bytes != 10*66 {
+		t.Errorf("expected 10 entries, 4 chains and 660 bytes, got %d, %d and %d", tally.entries, len(tally.chains), tally.bytes)
+	}
+	for _, test := range []struct {
+		policy BlockPolicy
+		full   bool
+	}{
+		{BlockPolicy{}, false},
+		{BlockPolicy{Interval: time.Second}, false},
+		{BlockPolicy{MaxEntries: 10}, true},
+		{BlockPolicy{MaxEntries: 11}, false},
+		{BlockPolicy{MaxChains: 4}, true},
+		{BlockPolicy{MaxChains: 5}, false},
+		{BlockPolicy{MaxBytes: 660}, true},
+		{BlockPolicy{MaxBytes: 661}, false},
+		{BlockPolicy{MaxEntries: 100, MaxChains: 4}, true}, // Any limit reached closes the block
+	} {
+		if test.policy.full(&tally) != test.full {
+			t.Errorf("policy %+v should say full is %v", test.policy, test.full)
+		}
+	}
+	tally.reset()
+	if (&BlockPolicy{MaxEntries: 1, MaxChains: 1, MaxBytes: 1}).full(&tally) {
+		t.Error("an empty block should not be full")
+	}
+}
+
+func TestMaxEntriesPolicy(t *testing.T) {
+	r, shutdown := startTestRouter(3, &BlockPolicy{MaxEntries: 100})
+	defer shutdown()
+
+	for i := 0; i < 350; i++ {
+		r.EntryHashStream <- getTestEntry(i, 20)
+	}
+	g, err := r.CloseBlock(context.Background()) // The 50 left over
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Height != 3 {
+		t.Errorf("expected 3 blocks of 100 entries before the one closed, but closed height %d", g.Height)
+	}
+	for height := types.BlockHeight(0); height <= 3; height++ {
+		if _, err := GetGlobalRoot(r.DB, height); err != nil {
+			t.Errorf("no global root for height %d: %v", height, err)
+		}
+	}
+	var total int64
+	for _, acc := range r.ACCs {
+		entries, _ := acc.Counts()
+		total += entries
+	}
+	if total != 350 {
+		t.Errorf("expected 350 entries recorded, got %d", total)
+	}
+}
+
+func TestMaxChainsPolicy(t *testing.T) {
+	r, shutdown := startTestRouter(2, &BlockPolicy{MaxChains: 5})
+	defer shutdown()
+
+	for i := 0; i < 100; i++ { // 10 chains, so every block covers 5 of them
+		r.EntryHashStream <- getTestEntry(i/10, 1000)
+	}
+	g, err := r.CloseBlock(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Height != 2 { // Chains 0-4 sealed height 0, chains 5-9 height 1, and nothing is left for height 2
+		t.Errorf("expected height 2 to be closed, got %d", g.Height)
+	}
+}
+
+func TestIntervalPolicy(t *testing.T) {
+	r, shutdown := startTestRouter(2, &BlockPolicy{Interval: 20 * time.Millisecond})
+	for i := 0; i < 10; i++ {
+		r.EntryHashStream <- getTestEntry(i, 3)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := GetGlobalRoot(r.DB, 2); err == nil {
+			break // The timer has closed a few blocks
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the interval policy isn't closing blocks")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	shutdown()
+	if _, err := r.CloseBlock(context.Background()); err == nil {
+		t.Error("CloseBlock should fail once the router has stopped")
+	}
+}
+
+func TestExternalPolicy(t *testing.T) {
+	r, shutdown := startTestRouter(2, &BlockPolicy{}) // Only CloseBlock closes blocks
+	defer shutdown()
+
+	for block := 0; block < 3; block++ {
+		for i := 0; i < 10; i++ {
+			r.EntryHashStream <- getTestEntry(block*10+i, 3)
+		}
+		g, err := r.CloseBlock(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g.Height != types.BlockHeight(block) || len(g.Roots) != 2 {
+			t.Errorf("expected height %d over 2 accumulators, got height %d over %d", block, g.Height, len(g.Roots))
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := r.CloseBlock(ctx); err == nil {
+		t.Error("CloseBlock should fail if the context is done")
+	}
+}
@@ -0,0 +1,75 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/accumulator"
+)
+
+// rebalance
+// Move every chain to the accumulator the strategy now routes it to.  Any block in flight (replayed from
+// a write-ahead log) is sealed first, so the chains are moved at a block boundary.  The accumulators must
+// not be running.  accs holds every accumulator with chains, including any being removed.
+func (r *Router) rebalance(accs []*accumulator.Accumulator) error {
+	if err := r.sealPending(accs); err != nil {
+		return err
+	}
+	for from, acc := range accs {
+		chains, err := acc.Chains()
+		if err != nil {
+			return err
+		}
+		for _, chainID := range chains {
+			to := r.Strategy.Route(chainID)
+			if to == from {
+				continue
+			}
+			if err := accumulator.MigrateChain(acc.DB, accs[to].DB, chainID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// sealPending
+// If any accumulator has a block in flight, seal the height on every accumulator and record its global root,
+// as closeBlock does while the router runs.  The accumulators must not be running.
+func (r *Router) sealPending(accs []*accumulator.Accumulator) error {
+	pending := false
+	for _, acc := range accs {
+		pending = pending || acc.Pending()
+	}
+	if !pending {
+		return nil
+	}
+	if err := alignHeights(accs); err != nil {
+		return err
+	}
+	results := make([]*accumulator.BlockResult, len(accs))
+	for i, acc := range accs {
+		results[i] = acc.Seal()
+	}
+	_, err := r.recordBlock(results)
+	return err
+}
+
+// alignHeights
+// Bring every accumulator up to the height of the highest, so they all seal the same heights, and a global
+// root can be built for every height.  Accumulators that were just added start at the height of the others.
+// Returns an error if any accumulator can't be brought to that height.
+func alignHeights(accs []*accumulator.Accumulator) error {
+	var height = accs[0].Height()
+	for _, acc := range accs {
+		if acc.Height() > height {
+			height = acc.Height()
+		}
+	}
+	for i, acc := range accs {
+		if err := acc.AlignHeight(height); err != nil {
+			return errors.New(fmt.Sprintf("accumulator %d can't be aligned with the others: %v", i, err))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package router
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/accumulator"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/database"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/node"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
+	dbm "github.com/tendermint/tm-db"
+)
+
+// startTestAccumulator
+// Start an accumulator over the given database, as the router would
+func startTestAccumulator(db *database.DB, i int) *accumulator.Accumulator {
+	acc := new(accumulator.Accumulator)
+	chainID := types.Hash(sha256.Sum256([]byte(fmt.Sprintf("Accumulator %d", i))))
+	acc.Init(db, &chainID)
+	return acc
+}
+
+func TestRebalance(t *testing.T) {
+	var dbs []*database.DB
+	for i := 0; i < 3; i++ {
+		db := new(database.DB)
+		db.InitDB(dbm.NewMemDB())
+		dbs = append(dbs, db)
+	}
+	var chains []types.Hash
+	for c := 0; c < 20; c++ {
+		chains = append(chains, sha256.Sum256([]byte(fmt.Sprint("chain ", c))))
+	}
+
+	// Record a block of entries over two accumulators
+	r := &Router{Strategy: &Modulo{Accumulators: 2}}
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		acc := startTestAccumulator(dbs[i], i)
+		r.ACCs = append(r.ACCs, acc)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := acc.Run(ctx); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	for _, chainID := range chains {
+		var entry node.EntryHash
+		entry.ChainID = chainID
+		entry.EntryHash = sha256.Sum256(chainID[:])
+		r.route(entry)
+	}
+	r.endBlock(context.Background())
+	cancel()
+	wg.Wait()
+
+	// Restart over three accumulators, and move the chains
+	var accs []*accumulator.Accumulator
+	for i := 0; i < 3; i++ {
+		accs = append(accs, startTestAccumulator(dbs[i], i))
+	}
+	r = &Router{Strategy: &Modulo{Accumulators: 3}}
+	if err := r.rebalance(accs); err != nil {
+		t.Fatal(err)
+	}
+	total := 0
+	for i, acc := range accs {
+		held, err := acc.Chains()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, chainID := range held {
+			if r.Strategy.Route(chainID) != i {
+				t.Errorf("chain %x is in accumulator %d, but is routed to %d", chainID[:4], i, r.Strategy.Route(chainID))
+			}
+		}
+		total += len(held)
+	}
+	if total != len(chains) {
+		t.Errorf("expected %d chains after the move, found %d", len(chains), total)
+	}
+
+	// The new accumulator catches up with the height of the others
+	if err := alignHeights(accs); err != nil {
+		t.Fatal(err)
+	}
+	for i, acc := range accs {
+		if acc.Height() != accs[0].Height() {
+			t.Errorf("accumulator %d is at height %d, not %d", i, acc.Height(), accs[0].Height())
+		}
+	}
+}
+
+func TestRebalancePending(t *testing.T) {
+	dir := t.TempDir()
+	var chains []types.Hash
+	for c := 0; c < 20; c++ {
+		chains = append(chains, sha256.Sum256([]byte(fmt.Sprint("chain ", c))))
+	}
+
+	// Two accumulators "crash" with a block in flight at height 0, left in their write-ahead logs
+	previous := &Modulo{Accumulators: 2}
+	for i := 0; i < 2; i++ {
+		wal, err := accumulator.OpenWAL(filepath.Join(dir, fmt.Sprintf("acc%d.wal", i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, chainID := range chains {
+			if previous.Route(chainID) != i {
+				continue
+			}
+			var entry node.EntryHash
+			entry.ChainID = chainID
+			entry.EntryHash = sha256.Sum256(chainID[:])
+			if err := wal.Append(0, entry); err != nil {
+				t.Fatal(err)
+			}
+		}
+		wal.Close()
+	}
+
+	// Restart over three accumulators, which replays the blocks in flight, and move the chains
+	var accs []*accumulator.Accumulator
+	for i := 0; i < 3; i++ {
+		db := new(database.DB)
+		db.InitDB(dbm.NewMemDB())
+		acc := new(accumulator.Accumulator)
+		acc.WALPath = filepath.Join(dir, fmt.Sprintf("acc%d.wal", i))
+		chainID := types.Hash(sha256.Sum256([]byte(fmt.Sprintf("Accumulator %d", i))))
+		acc.Init(db, &chainID)
+		accs = append(accs, acc)
+	}
+	if !accs[0].Pending() || !accs[1].Pending() {
+		t.Fatal("the blocks in the write-ahead logs should be pending")
+	}
+	db := new(database.DB)
+	db.InitDB(dbm.NewMemDB())
+	r := &Router{DB: db, Strategy: &Modulo{Accumulators: 3}}
+	if err := r.rebalance(accs); err != nil {
+		t.Fatal(err)
+	}
+
+	// The height in flight was sealed on every accumulator, and has a global root like any other
+	g, err := GetGlobalRoot(db, 0)
+	if err != nil {
+		t.Fatalf("the height sealed before moving chains should have a global root: %v", err)
+	}
+	if len(g.Roots) != len(accs) {
+		t.Fatalf("expected the global root over %d accumulators, got %d", len(accs), len(g.Roots))
+	}
+	for i, acc := range accs {
+		if acc.Pending() || acc.Height() != 1 {
+			t.Errorf("accumulator %d should have sealed height 0", i)
+		}
+		if directoryBlock, err := node.GetDirectoryBlock(acc.DB, 0); err != nil || *directoryBlock.GetMDRoot() != g.Roots[i] {
+			t.Errorf("accumulator %d should have sealed height 0 with the root in the global root", i)
+		}
+	}
+}
+
+func TestAlignHeightsPending(t *testing.T) {
+	walPath := filepath.Join(t.TempDir(), "acc0.wal")
+	wal, err := accumulator.OpenWAL(walPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wal.Append(0, getTestEntry(0, 2)); err != nil {
+		t.Fatal(err)
+	}
+	wal.Close()
+
+	// One accumulator with a block in flight at height 0, and one already at height 5
+	var accs []*accumulator.Accumulator
+	for i := 0; i < 2; i++ {
+		db := new(database.DB)
+		db.InitDB(dbm.NewMemDB())
+		acc := new(accumulator.Accumulator)
+		if i == 0 {
+			acc.WALPath = walPath
+		}
+		chainID := types.Hash(sha256.Sum256([]byte(fmt.Sprintf("Accumulator %d", i))))
+		acc.Init(db, &chainID)
+		accs = append(accs, acc)
+	}
+	if err := accs[1].AlignHeight(5); err != nil {
+		t.Fatal(err)
+	}
+
+	// The block in flight can't be moved to height 5, so nothing is sealed, and the reason is returned
+	db := new(database.DB)
+	db.InitDB(dbm.NewMemDB())
+	r := &Router{DB: db, Strategy: &Modulo{Accumulators: 2}}
+	if err := r.sealPending(accs); err == nil || !strings.Contains(err.Error(), "aligned") {
+		t.Errorf("expected an error aligning the accumulators, got %v", err)
+	}
+	if !accs[0].Pending() || accs[0].Height() != 0 || accs[1].Height() != 5 {
+		t.Error("nothing should be sealed when the accumulators can't be aligned")
+	}
+}
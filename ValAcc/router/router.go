@@ -1,109 +1,464 @@
 package router
 
 import (
-	"crypto/sha256"
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
-	"github.com/dustin/go-humanize"
 	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/accumulator"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/anchor"
 	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/database"
 	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/node"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/remote"
 	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
-	dbm "github.com/tendermint/tm-db"
+	"github.com/dustin/go-humanize"
 )
 
-// The router is used to configure a set of accumulators to distribute the construction of merkle DAGs.  Here
-// we are distributing the work over go routines, but in a production system we would distribute the load over
-// a network.
+// The router is used to configure a set of accumulators to distribute the construction of merkle DAGs.  The
+// work can be distributed over go routines (Init), or over accumulators running in other processes, and
+// reached over the network (InitRemote).
+
+// Accumulator
+// What the router needs of an accumulator.  Both an *accumulator.Accumulator running in this process, and
+// a *remote.Client talking to an accumulator in another process, are Accumulators.
+type Accumulator interface {
+	GetEntryFeed() chan node.EntryHash                              // Entries sent here are recorded
+	EndBlock(ctx context.Context) (*accumulator.BlockResult, error) // Seal the block in flight
+	Run(ctx context.Context) error                                  // Record entries until the context is done
+	Counts() (entries, chains int64)                                // Counts as of the last block sealed
+}
 
 type Router struct {
-	EntryHashStream chan node.EntryHash        // Stream of hashes to record
-	DBs             []*database.DB             // Databases where hashes are recorded
-	ACCs            []*accumulator.Accumulator // Accumulators to record hashes
+	EntryHashStream chan node.EntryHash // Stream of hashes to record
+	DBs             []*database.DB      // Databases where hashes are recorded
+	DB              *database.DB        // The router's database, holding the global roots
+	ACCs            []Accumulator       // Accumulators to record hashes
 	EntryFeeds      []chan node.EntryHash
-	Controls        []chan bool
-	MDFeeds         []chan *types.Hash
+	Anchor          *anchor.Scheduler // If set, the global roots are anchored through the Scheduler
+	Strategy        ShardStrategy     // Routes chains to accumulators; set before Init, or loaded by Init
+	Policy          *BlockPolicy      // When to close blocks; DefaultBlockPolicy if nil
+	Config          *Config           // Where the databases are kept; DefaultConfig() if nil when Init is called
+
+	channels      sync.Once                  // Makes closeRequests and stopped
+	closeRequests chan chan closeResult      // Requests from CloseBlock, answered by Run
+	stopped       chan struct{}              // Closed when Run stops taking requests
+	unsealed      []*accumulator.BlockResult // Results of a height some accumulators failed to seal
+}
+
+// closeBlock
+// Close the block on every accumulator, so they all seal the same height, then record the global root for
+// the height and report the totals so far.  If any accumulator failed to seal the last height, it seals that
+// height first, and the global root of that height is recorded.  Until it does, no accumulator seals the next
+// height, so the accumulators never drift apart in height.
+func (r *Router) closeBlock() (*GlobalRoot, error) {
+	if err := r.finishUnsealed(); err != nil {
+		return nil, err
+	}
+	results := r.endBlock(context.Background())
+	for _, result := range results {
+		if result.Err != nil { // Left open on that accumulator, and sealed again by the next closeBlock
+			r.unsealed = results
+			break
+		}
+	}
+	g, err := r.recordBlock(results)
+
+	var totalEntries, totalChains int64
+	for _, acc := range r.ACCs {
+		entries, chains := acc.Counts()
+		totalEntries += entries
+		totalChains += chains
+	}
+	secs := time.Now().Unix() - types.StartApp.Unix() + 1
+	fmt.Printf("Total Entries Written %s to %s total chains, @ %s tps\n",
+		humanize.Comma(totalEntries),
+		humanize.Comma(totalChains),
+		humanize.Comma(totalEntries/secs))
+	return g, err
+}
+
+// CloseBlock
+// Close the block in flight now, whatever the BlockPolicy, and return the global root of the height sealed.
+// Returns an error if the context is done first, if Run is not running, or if any accumulator failed to
+// seal the block.
+func (r *Router) CloseBlock(ctx context.Context) (*GlobalRoot, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.initChannels()
+	request := make(chan closeResult, 1) // Buffered, so Run never stalls if we walk away
+	select {
+	case r.closeRequests <- request:
+	case <-r.stopped:
+		return nil, errors.New("the router has stopped")
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	select {
+	case result := <-request:
+		return result.global, result.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// closeResult
+// The answer to a CloseBlock
+type closeResult struct {
+	global *GlobalRoot
+	err    error
+}
+
+// initChannels
+// Make the channels CloseBlock uses to reach Run, the first time either needs them
+func (r *Router) initChannels() {
+	r.channels.Do(func() {
+		r.closeRequests = make(chan chan closeResult)
+		r.stopped = make(chan struct{})
+	})
+}
+
+// recordBlock
+// Report what each accumulator sealed at a height, and record the global root for the height
+func (r *Router) recordBlock(results []*accumulator.BlockResult) (*GlobalRoot, error) {
+	for i, result := range results {
+		if result.Err != nil {
+			fmt.Printf("Accumulator %d failed to seal block %d: %v\n", i, result.Height, result.Err)
+			continue
+		}
+		if result.Refused > 0 {
+			fmt.Printf("Accumulator %d refused %d entries it could not log or check against its database\n", i, result.Refused)
+		}
+		fmt.Printf("Merkle DAG Root hash for %d is %x\n", i, result.MDRoot)
+	}
+	return r.recordGlobalRoot(results)
+}
+
+// recordGlobalRoot
+// Combine the roots of all the accumulators into the global root for the height, write it to the
+// router's database, and anchor it.  If any accumulator failed to seal its block, there is no global root
+// for the height.
+func (r *Router) recordGlobalRoot(results []*accumulator.BlockResult) (*GlobalRoot, error) {
+	g, err := NewGlobalRoot(results)
+	if err != nil {
+		fmt.Printf("No global root: %v\n", err)
+		return nil, err
+	}
+	if err := g.Put(r.DB); err != nil {
+		fmt.Printf("Failed to write the global root for height %d: %v\n", g.Height, err)
+		return nil, err
+	}
+	fmt.Printf("Global root for height %d is %x\n", g.Height, g.MDRoot)
+	if r.Anchor != nil {
+		if err := r.Anchor.Submit(context.Background(), g.MDRoot); err != nil {
+			fmt.Printf("Failed to submit the global root for height %d to be anchored: %v\n", g.Height, err)
+		}
+	}
+	return g, nil
+}
+
+// finishUnsealed
+// Seal again the block on each accumulator that failed to seal the last height, and once every accumulator
+// has sealed it, record its global root.  Returns an error if any accumulator still fails.
+func (r *Router) finishUnsealed() error {
+	if r.unsealed == nil {
+		return nil
+	}
+	for i, result := range r.unsealed {
+		if result.Err == nil {
+			continue
+		}
+		retry, err := r.ACCs[i].EndBlock(context.Background())
+		if retry == nil {
+			retry = &accumulator.BlockResult{Height: result.Height, Err: err}
+		}
+		r.unsealed[i] = retry
+		if retry.Err != nil {
+			return errors.New(fmt.Sprintf("accumulator %d still fails to seal height %d: %v", i, retry.Height, retry.Err))
+		}
+	}
+	results := r.unsealed
+	r.unsealed = nil
+	_, err := r.recordBlock(results)
+	return err
 }
 
-func (r *Router) blockTimer() {
-	blkCnt := 1
-	for { // Process Blocks
-		time.Sleep(10 * time.Second) // Create a block for some period of time.
-		fmt.Println("EOB", blkCnt)
-		// The Control channel only has one space.  Sending true indicates to the accumulator that it is
-		// time to seal off a block, do all that indexing, and start the next block.  Sending an immediate
-		// false will stall this loop until the accumulator comes around to pull that false out of the
-		// channel, but will not stall this loop.  So we send another false immediately so this loop will
-		// also stall.  False in the Control panel is really just a noop, so sending the two false indicators
-		// keeps the Process Blocks loop here and the block generator in the accumulator in sync.
-		for _, ctl := range r.Controls {
-			ctl <- true // Send true to Control to end the block
-		}
-		for _, ctl := range r.Controls {
-			ctl <- false // Block timing until the block is processed.
-		}
-		for _, ctl := range r.Controls {
-			ctl <- false // Block timing until the block is processed.
-		}
-		for i, mdFeed := range r.MDFeeds {
-			fmt.Printf("Merkle DAG Root hash for %d is %x\n", i, *<-mdFeed)
-		}
-		blkCnt++
-		var totalEntries, totalChains int64
-
-		for _, acc := range r.ACCs {
-			totalEntries += acc.EntryCnt.Load()
-			totalChains += acc.ChainCnt.Load()
-		}
-		secs := time.Now().Unix() - types.StartApp.Unix() + 1
-		fmt.Printf("Total Entries Written %s to %s total chains, @ %s tps\n",
-			humanize.Comma(totalEntries),
-			humanize.Comma(totalChains),
-			humanize.Comma(totalEntries/secs))
+// endBlock
+// Seal the current block on all the accumulators in parallel, and return their results in the
+// order of r.ACCs.  An accumulator that fails to seal reports the failure in its result's Err.
+func (r *Router) endBlock(ctx context.Context) []*accumulator.BlockResult {
+	results := make([]*accumulator.BlockResult, len(r.ACCs))
+	var wg sync.WaitGroup
+	for i, acc := range r.ACCs {
+		wg.Add(1)
+		go func(i int, acc Accumulator) {
+			defer wg.Done()
+			result, err := acc.EndBlock(ctx)
+			if result == nil {
+				result = &accumulator.BlockResult{Err: err}
+			}
+			results[i] = result
+		}(i, acc)
 	}
+	wg.Wait()
+	return results
 }
 
 // Init
-// Allocate a given number of accumulators to record hashes
-func (r *Router) Init(entryHashStream chan node.EntryHash, NumAccumulator int) {
+// Allocate a given number of accumulators to record hashes, with their databases kept as the Config says.
+// Returns an error if any database can't be opened, or the chains can't be moved to the accumulators the
+// shard strategy routes them to.  On an error, every database opened is closed again.
+func (r *Router) Init(entryHashStream chan node.EntryHash, NumAccumulator int) (err error) {
+	if r.Config == nil {
+		r.Config = DefaultConfig()
+	}
 	r.EntryHashStream = entryHashStream
-	for i := 0; i < NumAccumulator; i++ {
-		acc := new(accumulator.Accumulator)
-		r.ACCs = append(r.ACCs, acc)
-		db := new(database.DB)
-        //creat tendermint database
-		str := fmt.Sprintf("accumulator_%d.db", i);
-		//badger requires go build -tags badgerdb
-		//tmDB, err := dbm.NewDB(str,dbm.BadgerDBBackend,str)
-		//tmDB, err := dbm.NewDB(str,dbm.CLevelDBBackend,str)
-		//tmDB, err := dbm.NewDB(str,dbm.MemDBBackend,str)
-		tmDB, err := dbm.NewDB(str,dbm.GoLevelDBBackend,str)
+	if r.DB, err = r.Config.OpenRouterDB(); err != nil {
+		return err
+	}
+	var accs []*accumulator.Accumulator
+	defer func() {
+		if err != nil { // Leave nothing open behind a failure
+			for _, acc := range accs {
+				acc.Close()
+			}
+			r.DB.Close()
+		}
+	}()
+	previous, err := r.initStrategy(NumAccumulator)
+	if err != nil {
+		return err
+	}
+
+	// Accumulators being removed are opened too, so their chains can be moved to the accumulators kept
+	numOpen := NumAccumulator
+	if previous != nil && previous.Count() > numOpen {
+		numOpen = previous.Count()
+	}
+	for i := 0; i < numOpen; i++ {
+		acc, err := r.Config.OpenAccumulator(i)
 		if err != nil {
-			fmt.Errorf("failed to create accumulator database: %w", err)
-			return
+			return err
 		}
+		accs = append(accs, acc)
+	}
 
-		r.DBs = append(r.DBs, db)
-		db.InitDB(tmDB)
+	if previous != nil && !bytes.Equal(previous.Marshal(), r.Strategy.Marshal()) {
+		if err := r.rebalance(accs); err != nil {
+			return errors.New(fmt.Sprintf("failed to move the chains to their new accumulators: %v", err))
+		}
+	}
+	for _, acc := range accs[NumAccumulator:] { // Everything has been moved off the accumulators removed
+		if err := acc.Close(); err != nil {
+			fmt.Printf("failed to close a removed accumulator: %v\n", err)
+		}
+	}
+	accs = accs[:NumAccumulator]
+	if err := alignHeights(accs); err != nil {
+		return err
+	}
 
-		chainID := types.Hash(sha256.Sum256([]byte(fmt.Sprintf("Accumulator %d", i))))
+	// Only once the chains are where the strategy routes them is the strategy saved.  If we die moving the
+	// chains, the next start moves them again.
+	if err := SaveStrategy(r.DB, r.Strategy); err != nil {
+		return err
+	}
+	for _, acc := range accs {
+		r.ACCs = append(r.ACCs, acc)
+		r.DBs = append(r.DBs, acc.DB)
+		r.EntryFeeds = append(r.EntryFeeds, acc.GetEntryFeed())
+	}
+	return nil
+}
 
-		entryFeed, control, mdHashes := acc.Init(r.DBs[i], &chainID)
-		r.EntryFeeds = append(r.EntryFeeds, entryFeed)
-		r.Controls = append(r.Controls, control)
-		r.MDFeeds = append(r.MDFeeds, mdHashes)
-		go acc.Run()
+// InitRemote
+// Route to accumulators running in other processes, served at the given addresses (see remote.Server).  The
+// router's database (kept as the Config says) and shard strategy are handled as by Init, but the chains of
+// remote accumulators can't be moved by the router.  So if the strategy changed since the last run,
+// InitRemote fails, and the chains must first be moved on the accumulators' hosts (see cmd/movechain).
+func (r *Router) InitRemote(ctx context.Context, entryHashStream chan node.EntryHash, addrs []string) (err error) {
+	if r.Config == nil {
+		r.Config = DefaultConfig()
+	}
+	r.EntryHashStream = entryHashStream
+	if r.DB, err = r.Config.OpenRouterDB(); err != nil {
+		return err
+	}
+	var clients []*remote.Client
+	defer func() {
+		if err != nil {
+			for _, client := range clients {
+				client.Close()
+			}
+			r.DB.Close()
+		}
+	}()
+	previous, err := r.initStrategy(len(addrs))
+	if err != nil {
+		return err
+	}
+	if previous != nil && !bytes.Equal(previous.Marshal(), r.Strategy.Marshal()) {
+		return errors.New("the shard strategy changed, and the chains of remote accumulators can't be moved by the router")
+	}
+	if err := SaveStrategy(r.DB, r.Strategy); err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		client, err := remote.Dial(ctx, addr)
+		if err != nil {
+			return errors.New(fmt.Sprintf("failed to connect to the accumulator at %s: %v", addr, err))
+		}
+		clients = append(clients, client)
 	}
+	for _, client := range clients {
+		r.ACCs = append(r.ACCs, client)
+		r.EntryFeeds = append(r.EntryFeeds, client.GetEntryFeed())
+	}
+	return nil
 }
 
-func (r *Router) Run() {
-	go r.blockTimer()
-	cnt := len(r.ACCs) // Count of accumulators
+// Run
+// Start the accumulators, then route entries to them until the context is done, closing blocks as the
+// Policy says, or when asked by CloseBlock.  On shutdown, the entries still in the EntryHashStream are
+// routed, the block in flight (if any entries were routed into it) is closed so its height gets a global
+// root, every accumulator closes its database, the roots waiting to be anchored are anchored, the router's
+// database is closed, and only then does Run return.
+func (r *Router) Run(ctx context.Context) {
+	accCtx, stopAccs := context.WithCancel(context.Background())
+	var accs sync.WaitGroup
+	for i, acc := range r.ACCs {
+		accs.Add(1)
+		go func(i int, acc Accumulator) {
+			defer accs.Done()
+			if err := acc.Run(accCtx); err != nil {
+				fmt.Printf("Accumulator %d failed to shut down cleanly: %v\n", i, err)
+			}
+		}(i, acc)
+	}
+
+	anchorCtx, stopAnchor := context.WithCancel(context.Background())
+	anchorDone := make(chan struct{})
+	if r.Anchor != nil {
+		go func() {
+			if err := r.Anchor.Run(anchorCtx); err != nil {
+				fmt.Printf("Anchoring failed to shut down cleanly: %v\n", err)
+			}
+			close(anchorDone)
+		}()
+	} else {
+		close(anchorDone)
+	}
+
+	policy := DefaultBlockPolicy
+	if r.Policy != nil {
+		policy = *r.Policy
+	}
+	var interval <-chan time.Time // Never fires if the policy has no Interval
+	var timer *time.Timer
+	if policy.Interval > 0 {
+		timer = time.NewTimer(policy.Interval)
+		defer timer.Stop()
+		interval = timer.C
+	}
+
+	// Blocks are only closed here, one at a time, so every accumulator seals the same height
+	var tally blockTally
+	seal := func() (*GlobalRoot, error) {
+		g, err := r.closeBlock()
+		tally.reset()
+		if timer != nil { // The interval counts from the last block closed, whatever closed it
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+			timer.Reset(policy.Interval)
+		}
+		return g, err
+	}
+	routeEntry := func(entry node.EntryHash) {
+		r.route(entry)
+		tally.add(entry)
+		if policy.full(&tally) {
+			seal()
+		}
+	}
+
+	r.initChannels()
+routing:
 	for {
-		entry := <-r.EntryHashStream
-		chainNumber := int(entry.ChainID[0])<<8 + int(entry.ChainID[1])
-		idx := chainNumber % cnt
-		r.ACCs[idx].GetEntryFeed() <- entry
+		select {
+		case entry := <-r.EntryHashStream:
+			routeEntry(entry)
+		case <-interval:
+			seal()
+		case request := <-r.closeRequests:
+			g, err := seal()
+			request <- closeResult{global: g, err: err}
+		case <-ctx.Done():
+			break routing // Stop taking new entries
+		}
+	}
+	close(r.stopped)
+
+	for len(r.EntryHashStream) > 0 {
+		routeEntry(<-r.EntryHashStream)
+	}
+	if tally.entries > 0 || r.unsealed != nil { // Close the last block here, not in the accumulators, so it has a global root
+		seal()
+	}
+	stopAccs()
+	accs.Wait()
+	stopAnchor() // Anchor the roots still waiting
+	<-anchorDone
+	if err := r.DB.Close(); err != nil {
+		fmt.Printf("Failed to close the router database: %v\n", err)
+	}
+}
+
+// route
+// Send the entry to the accumulator responsible for its chain
+func (r *Router) route(entry node.EntryHash) {
+	r.ACCs[r.Strategy.Route(entry.ChainID)].GetEntryFeed() <- entry
+}
+
+// initStrategy
+// Pick the shard strategy.  A Strategy set before Init is used.  Otherwise the strategy saved by the last run
+// is used, adjusted to the count of accumulators, or Modulo if there is none.  Returns the strategy saved by
+// the last run, if any, so the chains can be moved if the strategy changed.
+func (r *Router) initStrategy(numAccumulator int) (previous ShardStrategy, err error) {
+	if numAccumulator < 1 {
+		return nil, errors.New(fmt.Sprintf("a router needs at least one accumulator, not %d", numAccumulator))
+	}
+	previous, err = LoadStrategy(r.DB)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case r.Strategy != nil:
+		if previous != nil && !bytes.Equal(previous.Marshal(), r.Strategy.Marshal()) {
+			fmt.Println("The shard strategy has changed, so some chains will move to other accumulators")
+		}
+	case previous != nil:
+		r.Strategy = previous
+		if previous.Count() != numAccumulator {
+			fmt.Printf("The count of accumulators changed from %d to %d, so some chains will move\n",
+				previous.Count(), numAccumulator)
+			r.Strategy = previous.WithCount(numAccumulator)
+		}
+	default:
+		r.Strategy = &Modulo{Accumulators: numAccumulator}
+	}
+	if err := validateStrategy(r.Strategy); err != nil {
+		return nil, err
+	}
+	if r.Strategy.Count() != numAccumulator {
+		return nil, errors.New(fmt.Sprintf("the shard strategy routes to %d accumulators, not %d",
+			r.Strategy.Count(), numAccumulator))
 	}
+	return previous, nil
 }
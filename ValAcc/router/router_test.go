@@ -0,0 +1,184 @@
+package router
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/accumulator"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/database"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/node"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/remote"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
+	dbm "github.com/tendermint/tm-db"
+)
+
+func TestRemoteRouter(t *testing.T) {
+	const numAcc = 3
+	db := new(database.DB)
+	db.InitDB(dbm.NewMemDB())
+	stream := make(chan node.EntryHash, 100)
+	r := &Router{DB: db, EntryHashStream: stream, Strategy: &Modulo{Accumulators: numAcc}}
+
+	// Several accumulators served on loopback, as if each were its own process
+	serverCtx, stopServers := context.WithCancel(context.Background())
+	defer stopServers()
+	for i := 0; i < numAcc; i++ {
+		accDB := new(database.DB)
+		accDB.InitDB(dbm.NewMemDB())
+		acc := startTestAccumulator(accDB, i)
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		go remote.NewServer(acc, 64).Serve(serverCtx, listener)
+		client, err := remote.Dial(context.Background(), listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.ACCs = append(r.ACCs, client)
+	}
+
+	ctx, shutdown := context.WithCancel(context.Background())
+	routerDone := make(chan struct{})
+	go func() {
+		r.Run(ctx)
+		close(routerDone)
+	}()
+	for i := 0; i < 1000; i++ {
+		var entry node.EntryHash
+		entry.ChainID = sha256.Sum256([]byte(fmt.Sprint("chain ", i%50)))
+		entry.EntryHash = sha256.Sum256([]byte(fmt.Sprint("entry ", i)))
+		stream <- entry
+	}
+	shutdown()
+	<-routerDone
+
+	var total int64
+	for _, acc := range r.ACCs {
+		entries, _ := acc.Counts()
+		total += entries
+	}
+	if total != 1000 {
+		t.Errorf("expected 1000 entries recorded by the remote accumulators, got %d", total)
+	}
+}
+
+func TestShutdownGlobalRoot(t *testing.T) {
+	r, shutdown := startTestRouter(2, &BlockPolicy{}) // Only CloseBlock and shutdown close blocks
+	for i := 0; i < 10; i++ {
+		r.EntryHashStream <- getTestEntry(i, 3)
+	}
+	if _, err := r.CloseBlock(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for i := 10; i < 20; i++ {
+		r.EntryHashStream <- getTestEntry(i, 3)
+	}
+	shutdown()
+
+	// The block in flight at shutdown is the last height, and has a global root like any other
+	g, err := GetGlobalRoot(r.DB, 1)
+	if err != nil {
+		t.Fatalf("the last height sealed on shutdown should have a global root: %v", err)
+	}
+	if len(g.Roots) != 2 {
+		t.Errorf("expected the global root over 2 accumulators, got %d", len(g.Roots))
+	}
+	for i, acc := range r.ACCs {
+		db := acc.(*accumulator.Accumulator).DB
+		if directoryBlock, err := node.GetDirectoryBlock(db, 1); err != nil || *directoryBlock.GetMDRoot() != g.Roots[i] {
+			t.Errorf("accumulator %d should have sealed height 1 with the root in the global root", i)
+		}
+		if _, err := node.GetDirectoryBlock(db, 2); err == nil {
+			t.Errorf("accumulator %d should not seal another height without a global root on shutdown", i)
+		}
+	}
+}
+
+// failingDB
+// An in memory database whose batches fail to write while fail is set, as if the disk were full
+type failingDB struct {
+	*dbm.MemDB
+	fail bool
+}
+
+func (f *failingDB) NewBatch() dbm.Batch {
+	return &failingBatch{Batch: f.MemDB.NewBatch(), db: f}
+}
+
+type failingBatch struct {
+	dbm.Batch
+	db *failingDB
+}
+
+func (b *failingBatch) WriteSync() error {
+	if b.db.fail {
+		return errors.New("disk full")
+	}
+	return b.Batch.WriteSync()
+}
+
+func TestSealFailure(t *testing.T) {
+	db := new(database.DB)
+	db.InitDB(dbm.NewMemDB())
+	r := &Router{DB: db, EntryHashStream: make(chan node.EntryHash), Strategy: &Modulo{Accumulators: 2}, Policy: &BlockPolicy{}}
+	failing := &failingDB{MemDB: dbm.NewMemDB()}
+	for i, memDB := range []dbm.DB{dbm.NewMemDB(), failing} {
+		accDB := new(database.DB)
+		accDB.InitDB(memDB)
+		r.ACCs = append(r.ACCs, startTestAccumulator(accDB, i))
+	}
+	ctx, shutdown := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		r.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		shutdown()
+		<-done
+	}()
+
+	// Accumulator 1 fails to write height 0, and again when the router retries, so no global root is recorded
+	// and accumulator 0 doesn't run ahead to height 1
+	failing.fail = true
+	for i := 0; i < 10; i++ {
+		r.EntryHashStream <- getTestEntry(i, 4)
+	}
+	for try := 0; try < 2; try++ {
+		if _, err := r.CloseBlock(context.Background()); err == nil {
+			t.Fatal("a height an accumulator failed to seal has no global root")
+		}
+	}
+	if _, err := node.GetDirectoryBlock(r.ACCs[0].(*accumulator.Accumulator).DB, 1); err == nil {
+		t.Error("accumulator 0 should wait at height 1 for accumulator 1 to seal height 0")
+	}
+
+	// Once the writes succeed, height 0 is sealed and has its global root, and the heights go on after it
+	failing.fail = false
+	for i := 10; i < 20; i++ {
+		r.EntryHashStream <- getTestEntry(i, 4)
+	}
+	for height := 1; height < 3; height++ {
+		g, err := r.CloseBlock(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if int(g.Height) != height {
+			t.Errorf("expected the global root of height %d, got %d", height, g.Height)
+		}
+	}
+	for height := types.BlockHeight(0); height < 3; height++ {
+		if _, err := GetGlobalRoot(r.DB, height); err != nil {
+			t.Errorf("height %d should have a global root: %v", height, err)
+		}
+	}
+}
+
+// A local accumulator must satisfy the router's interface as well as a remote one
+var _ Accumulator = new(accumulator.Accumulator)
+var _ Accumulator = new(remote.Client)
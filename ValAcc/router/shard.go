@@ -0,0 +1,260 @@
+package router
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/database"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
+)
+
+// ShardStrategy
+// Decides which accumulator records each chain.  Every entry of a chain must go to the same accumulator,
+// so a chain's history stays in one database.  The strategy is written to the router's database, so a
+// restart routes every chain exactly as before.
+type ShardStrategy interface {
+	Route(chainID types.Hash) int  // Index of the accumulator for the chain
+	Count() int                    // Count of accumulators routed to
+	WithCount(n int) ShardStrategy // The same strategy, with the same parameters, over n accumulators
+	Marshal() []byte               // Encoding of the strategy and its parameters; see UnmarshalStrategy
+}
+
+// Types of strategies, the first byte of their encodings
+const (
+	ShardModulo = byte(iota + 1)
+	ShardRing
+	ShardPinned
+)
+
+// shardKey
+// The key of the strategy in the ShardStrategy bucket of the router's database
+var shardKey = []byte("strategy")
+
+// Modulo
+// Routes by the first two bytes of the ChainID modulo the count of accumulators.  Simple, and even, but
+// changing the count of accumulators moves almost every chain.
+type Modulo struct {
+	Accumulators int // Count of accumulators
+}
+
+func (m *Modulo) Route(chainID types.Hash) int {
+	chainNumber := int(chainID[0])<<8 + int(chainID[1])
+	return chainNumber % m.Accumulators
+}
+
+func (m *Modulo) Count() int {
+	return m.Accumulators
+}
+
+func (m *Modulo) WithCount(n int) ShardStrategy {
+	return &Modulo{Accumulators: n}
+}
+
+func (m *Modulo) Marshal() (data []byte) {
+	data = append(data, ShardModulo)
+	return append(data, types.Uint32Bytes(uint32(m.Accumulators))...)
+}
+
+// Ring
+// Consistent hashing.  Each accumulator is placed on a ring of uint64 at VirtualNodes points, and a chain is
+// routed to the accumulator at the first point at or after the chain's place on the ring.  Adding or removing
+// an accumulator only moves the chains between its points and the points before them, about 1/n of them.
+type Ring struct {
+	Accumulators int         // Count of accumulators
+	VirtualNodes int         // Count of points on the ring for each accumulator
+	points       []ringPoint // Points on the ring, in order
+}
+
+type ringPoint struct {
+	place       uint64 // Place on the ring
+	accumulator int    // Accumulator at this place
+}
+
+// NewRing
+// Build a ring over the given count of accumulators, each at the given count of points
+func NewRing(accumulators, virtualNodes int) *Ring {
+	r := new(Ring)
+	r.Accumulators = accumulators
+	r.VirtualNodes = virtualNodes
+	for i := 0; i < accumulators; i++ {
+		for v := 0; v < virtualNodes; v++ {
+			h := sha256.Sum256([]byte(fmt.Sprintf("accumulator %d point %d", i, v)))
+			r.points = append(r.points, ringPoint{binary.BigEndian.Uint64(h[:]), i})
+		}
+	}
+	sort.Slice(r.points, func(i, j int) bool {
+		if r.points[i].place != r.points[j].place {
+			return r.points[i].place < r.points[j].place
+		}
+		return r.points[i].accumulator < r.points[j].accumulator
+	})
+	return r
+}
+
+func (r *Ring) Route(chainID types.Hash) int {
+	place := binary.BigEndian.Uint64(chainID[:]) // ChainIDs are hashes, so they are spread evenly on the ring
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i].place >= place })
+	if i == len(r.points) { // Past the last point, so wrap around to the first
+		i = 0
+	}
+	return r.points[i].accumulator
+}
+
+func (r *Ring) Count() int {
+	return r.Accumulators
+}
+
+func (r *Ring) WithCount(n int) ShardStrategy {
+	return NewRing(n, r.VirtualNodes)
+}
+
+func (r *Ring) Marshal() (data []byte) {
+	data = append(data, ShardRing)
+	data = append(data, types.Uint32Bytes(uint32(r.Accumulators))...)
+	return append(data, types.Uint32Bytes(uint32(r.VirtualNodes))...)
+}
+
+// Pinned
+// Routes the chains in Pins to the accumulators given, and every other chain by the underlying Strategy.
+// Used to isolate hot chains on accumulators of their own.
+type Pinned struct {
+	Strategy ShardStrategy      // Routes the chains not pinned
+	Pins     map[types.Hash]int // Accumulator for each pinned chain
+}
+
+func (p *Pinned) Route(chainID types.Hash) int {
+	if acc, ok := p.Pins[chainID]; ok {
+		return acc
+	}
+	return p.Strategy.Route(chainID)
+}
+
+func (p *Pinned) Count() int {
+	return p.Strategy.Count()
+}
+
+// WithCount
+// Pins to accumulators that no longer exist are dropped
+func (p *Pinned) WithCount(n int) ShardStrategy {
+	p2 := &Pinned{Strategy: p.Strategy.WithCount(n), Pins: make(map[types.Hash]int)}
+	for chainID, acc := range p.Pins {
+		if acc < n {
+			p2.Pins[chainID] = acc
+		}
+	}
+	return p2
+}
+
+// Marshal
+// The pins are sorted by ChainID, so a strategy always has the same encoding
+func (p *Pinned) Marshal() (data []byte) {
+	data = append(data, ShardPinned)
+	inner := p.Strategy.Marshal()
+	data = append(data, types.Uint16Bytes(uint16(len(inner)))...)
+	data = append(data, inner...)
+	var chains []types.Hash
+	for chainID := range p.Pins {
+		chains = append(chains, chainID)
+	}
+	sort.Slice(chains, func(i, j int) bool { return bytes.Compare(chains[i][:], chains[j][:]) < 0 })
+	data = append(data, types.Uint32Bytes(uint32(len(chains)))...)
+	for _, chainID := range chains {
+		data = append(data, chainID.Bytes()...)
+		data = append(data, types.Uint32Bytes(uint32(p.Pins[chainID]))...)
+	}
+	return data
+}
+
+// UnmarshalStrategy
+// Rebuild a strategy from its encoding.  Returns an error if the encoding is bad.
+func UnmarshalStrategy(data []byte) (s ShardStrategy, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New(fmt.Sprintf("ShardStrategy Failed to unmarshal %v", r))
+		}
+	}()
+	var accumulators uint32
+	switch data[0] {
+	case ShardModulo:
+		accumulators, _ = types.BytesUint32(data[1:])
+		s = &Modulo{Accumulators: int(accumulators)}
+	case ShardRing:
+		var virtualNodes uint32
+		accumulators, data = types.BytesUint32(data[1:])
+		virtualNodes, _ = types.BytesUint32(data)
+		s = NewRing(int(accumulators), int(virtualNodes))
+	case ShardPinned:
+		var innerLen uint16
+		innerLen, data = types.BytesUint16(data[1:])
+		inner, err := UnmarshalStrategy(data[:innerLen])
+		if err != nil {
+			return nil, err
+		}
+		data = data[innerLen:]
+		p := &Pinned{Strategy: inner, Pins: make(map[types.Hash]int)}
+		var numPins uint32
+		numPins, data = types.BytesUint32(data)
+		for i := uint32(0); i < numPins; i++ {
+			var chainID types.Hash
+			var acc uint32
+			data = chainID.Extract(data)
+			acc, data = types.BytesUint32(data)
+			p.Pins[chainID] = int(acc)
+		}
+		s = p
+	default:
+		return nil, errors.New(fmt.Sprintf("unknown shard strategy %d", data[0]))
+	}
+	if err := validateStrategy(s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// validateStrategy
+// Check that a strategy routes to at least one accumulator, that a ring has its points, and that every pin
+// is to one of the accumulators, so Route always returns the index of an accumulator
+func validateStrategy(s ShardStrategy) error {
+	if s.Count() < 1 {
+		return errors.New("a shard strategy needs at least one accumulator")
+	}
+	switch s := s.(type) {
+	case *Ring:
+		if s.VirtualNodes < 1 {
+			return errors.New("a ring needs at least one virtual node per accumulator")
+		}
+		if len(s.points) != s.Accumulators*s.VirtualNodes {
+			return errors.New("a ring must be built with NewRing")
+		}
+	case *Pinned:
+		if err := validateStrategy(s.Strategy); err != nil {
+			return err
+		}
+		for chainID, acc := range s.Pins {
+			if acc < 0 || acc >= s.Count() {
+				return errors.New(fmt.Sprintf("chain %x is pinned to accumulator %d of %d", chainID, acc, s.Count()))
+			}
+		}
+	}
+	return nil
+}
+
+// SaveStrategy
+// Write the strategy to the router's database
+func SaveStrategy(db database.Store, s ShardStrategy) error {
+	return db.Put(types.ShardStrategy, shardKey, s.Marshal())
+}
+
+// LoadStrategy
+// Read the strategy from the router's database.  Returns a nil strategy and no error if none was saved.
+func LoadStrategy(db *database.DB) (ShardStrategy, error) {
+	data := db.Get(types.ShardStrategy, shardKey)
+	if data == nil {
+		return nil, nil
+	}
+	return UnmarshalStrategy(data)
+}
@@ -0,0 +1,178 @@
+package router
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"testing"
+
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/database"
+	"github.com/AccumulateNetwork/ValidatorAccumulator/ValAcc/types"
+	dbm "github.com/tendermint/tm-db"
+)
+
+// getTestChains
+// Build some ChainIDs for the tests
+func getTestChains(cnt int) (chains []types.Hash) {
+	for i := 0; i < cnt; i++ {
+		chains = append(chains, sha256.Sum256([]byte(fmt.Sprint("chain ", i))))
+	}
+	return chains
+}
+
+// checkRoundTrip
+// The strategy must route every chain the same after it is marshaled and unmarshaled
+func checkRoundTrip(t *testing.T, s ShardStrategy, chains []types.Hash) {
+	s2, err := UnmarshalStrategy(s.Marshal())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(s2.Marshal(), s.Marshal()) || s2.Count() != s.Count() {
+		t.Fatalf("the strategy did not survive a round trip")
+	}
+	for _, chainID := range chains {
+		if s.Route(chainID) != s2.Route(chainID) {
+			t.Fatalf("the strategy routes differently after a round trip")
+		}
+	}
+}
+
+func TestModulo(t *testing.T) {
+	chains := getTestChains(1000)
+	m := &Modulo{Accumulators: 7}
+	for _, chainID := range chains {
+		if m.Route(chainID) != (int(chainID[0])<<8+int(chainID[1]))%7 {
+			t.Fatal("Modulo should route by the first two bytes of the ChainID")
+		}
+	}
+	checkRoundTrip(t, m, chains)
+}
+
+func TestRing(t *testing.T) {
+	chains := getTestChains(10000)
+	r := NewRing(8, 100)
+	counts := make([]int, 8)
+	for _, chainID := range chains {
+		counts[r.Route(chainID)]++
+	}
+	for i, cnt := range counts { // Expect 1250 each
+		if cnt < 800 || cnt > 1700 {
+			t.Errorf("accumulator %d has %d of 10000 chains; the ring is too uneven", i, cnt)
+		}
+	}
+	checkRoundTrip(t, r, chains)
+
+	// Adding an accumulator only moves chains to the new accumulator, and only about 1/9 of them
+	r9 := r.WithCount(9)
+	moved := 0
+	for _, chainID := range chains {
+		if from, to := r.Route(chainID), r9.Route(chainID); from != to {
+			moved++
+			if to != 8 {
+				t.Fatalf("a chain moved from accumulator %d to %d, not to the new accumulator", from, to)
+			}
+		}
+	}
+	if moved < 700 || moved > 1600 {
+		t.Errorf("expected about 1111 chains to move, %d moved", moved)
+	}
+}
+
+func TestPinned(t *testing.T) {
+	chains := getTestChains(100)
+	p := &Pinned{Strategy: NewRing(4, 50), Pins: map[types.Hash]int{chains[0]: 3, chains[1]: 3, chains[2]: 0}}
+	for i, chainID := range chains {
+		expected := p.Strategy.Route(chainID)
+		if acc, ok := p.Pins[chainID]; ok {
+			expected = acc
+		}
+		if p.Route(chainID) != expected {
+			t.Errorf("chain %d routed to the wrong accumulator", i)
+		}
+	}
+	checkRoundTrip(t, p, chains)
+
+	p3 := p.WithCount(3).(*Pinned)
+	if _, ok := p3.Pins[chains[0]]; ok || p3.Pins[chains[2]] != 0 || p3.Count() != 3 {
+		t.Error("pins to accumulators removed should be dropped, and the others kept")
+	}
+
+	for _, bad := range [][]byte{{}, {9}, {ShardRing, 0, 0, 0, 4, 0, 0, 0, 0}, {ShardModulo, 0, 0, 0, 0}} {
+		if _, err := UnmarshalStrategy(bad); err == nil {
+			t.Errorf("%x should not unmarshal", bad)
+		}
+	}
+}
+
+func TestInitStrategy(t *testing.T) {
+	db := new(database.DB)
+	db.InitDB(dbm.NewMemDB())
+
+	r := &Router{DB: db}
+	previous, err := r.initStrategy(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if previous != nil {
+		t.Error("there is no previous strategy on the first start")
+	}
+	if _, ok := r.Strategy.(*Modulo); !ok || r.Strategy.Count() != 4 {
+		t.Error("the default strategy should be Modulo")
+	}
+
+	r = &Router{DB: db, Strategy: NewRing(4, 20)}
+	if _, err := r.initStrategy(4); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveStrategy(db, r.Strategy); err != nil { // Init saves the strategy once the chains are moved
+		t.Fatal(err)
+	}
+
+	// A restart routes the same way, even without being told the strategy
+	r = &Router{DB: db}
+	previous, err = r.initStrategy(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(r.Strategy.Marshal(), NewRing(4, 20).Marshal()) {
+		t.Error("the saved strategy should be used on a restart")
+	}
+	if !bytes.Equal(previous.Marshal(), r.Strategy.Marshal()) {
+		t.Error("the previous strategy should be the saved strategy")
+	}
+
+	// And changing the count keeps the kind of strategy
+	r = &Router{DB: db}
+	previous, err = r.initStrategy(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(r.Strategy.Marshal(), NewRing(5, 20).Marshal()) {
+		t.Error("the saved strategy should be adjusted to the count of accumulators")
+	}
+	if previous.Count() != 4 {
+		t.Error("the previous strategy should route to the old count of accumulators")
+	}
+
+	r = &Router{DB: db, Strategy: &Modulo{Accumulators: 3}}
+	if _, err := r.initStrategy(5); err == nil {
+		t.Error("a strategy over the wrong count of accumulators should fail")
+	}
+
+	// A strategy given by the caller is checked as closely as one read from the database
+	pinned := &Pinned{Strategy: &Modulo{Accumulators: 3}, Pins: map[types.Hash]int{{1}: 3}}
+	r = &Router{DB: db, Strategy: pinned}
+	if _, err := r.initStrategy(3); err == nil {
+		t.Error("a chain pinned to an accumulator that doesn't exist should fail")
+	}
+	r = &Router{DB: db, Strategy: &Ring{Accumulators: 3, VirtualNodes: 20}}
+	if _, err := r.initStrategy(3); err == nil {
+		t.Error("a ring without its points should fail")
+	}
+	for _, n := range []int{0, -1} {
+		r = &Router{DB: db}
+		if _, err := r.initStrategy(n); err == nil {
+			t.Errorf("a router over %d accumulators should fail", n)
+		}
+	}
+}
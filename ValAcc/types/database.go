@@ -11,4 +11,10 @@ const (
 	EntryNode            = "entry Node"             // Key: entry.GetHash()   Value:  node where this entry is recorded
 	DirectoryBlockHeight = "directory block height" // Key: node.BHeight      Value:  Directory Block node
 	Node                 = "node"                   // Key: node.GetHash()    Value:  nodeHash
+	MDState              = "md state"               // Key: node.GetHash()    Value:  MDNode for the chain's MD at this node
+	MDRootNode           = "md root node"           // Key: node.GetMDRoot()  Value:  node with this MDRoot
+	ChainMoves           = "chain moves"            // Key: node.ChainID      Value:  accumulator.ChainMoves onto this accumulator
+	Anchor               = "anchor"                 // Key: global root       Value:  anchor.Record for the root
+	GlobalRoot           = "global root"            // Key: BlockHeight       Value:  router.GlobalRoot at the height
+	ShardStrategy        = "shard strategy"         // Key: "strategy"        Value:  router.ShardStrategy routing the chains
 )
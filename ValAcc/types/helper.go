@@ -100,5 +100,5 @@ func Uint64Bytes(i uint64) []byte {
 // Unmarshal a uint64 (big endian)
 func BytesUint64(data []byte) (uint64, []byte) {
 	return uint64(data[0])<<56 + uint64(data[1])<<48 + uint64(data[2])<<40 + uint64(data[3])<<32 +
-		uint64(data[4])<<24 + uint64(data[5])<<16 + uint64(data[6])<<8 + uint64(data[7]), data[4:]
+		uint64(data[4])<<24 + uint64(data[5])<<16 + uint64(data[6])<<8 + uint64(data[7]), data[8:]
 }
@@ -44,4 +44,14 @@ func TestHelperFunc(t *testing.T) {
 			t.Error("v2 r2 didn't match")
 		}
 	}
+	{
+		v1 := uint64(0x1122334455667788)
+		b := Uint64Bytes(v1)
+		b = append(b, Uint16Bytes(0x99aa)...)
+		r1, b := BytesUint64(b)
+		r2, b := BytesUint16(b)
+		if v1 != r1 || r2 != 0x99aa || len(b) != 0 {
+			t.Error("uint64 didn't round trip")
+		}
+	}
 }